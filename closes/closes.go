@@ -5,6 +5,7 @@ import (
 	"os"
 	"os/signal"
 	"sort"
+	"sync"
 	"syscall"
 )
 
@@ -17,13 +18,21 @@ type (
 	closes []ModuleClose
 )
 
-var closeHandler closes
+var (
+	closeHandler closes
+	// closeLock 组件 getter 会在请求中惰性创建并注册关闭方法, 与 Close 并发
+	closeLock sync.Mutex
+)
 
 const (
-	MQPriority     = 100
-	GormPriority   = 500
-	RedisPriority  = 500
-	AliLogPriority = 2000
+	// RegistryPriority 注册中心注销实例最先执行, 先摘除流量再关闭其他服务
+	RegistryPriority = 50
+	ServerPriority   = 60 // 注销实例后优雅关闭 http/grpc 服务, 等待处理中的请求结束
+	MQPriority       = 100
+	ClientPriority   = 300 // grpc/http 客户端在消息队列之后, 数据库之前关闭
+	GormPriority     = 500
+	RedisPriority    = 500
+	AliLogPriority   = 2000
 )
 
 func (c closes) Len() int           { return len(c) }
@@ -32,6 +41,8 @@ func (c closes) Swap(i, j int)      { c[i], c[j] = c[j], c[i] }
 
 // AddShutdown 增加程序结束时需要关闭的服务
 func AddShutdown(c ...ModuleClose) {
+	closeLock.Lock()
+	defer closeLock.Unlock()
 	closeHandler = append(closeHandler, c...)
 }
 
@@ -46,12 +57,15 @@ func SignalClose() {
 
 // Close 按照优先级调用关闭方法
 func Close() {
+	// 关闭过程中处理中的请求仍可能创建组件并调用 AddShutdown, 复制后释放锁再执行
+	closeLock.Lock()
 	sort.Sort(closeHandler)
-	if len(closeHandler) > 0 {
-		for _, f := range closeHandler {
-			fmt.Printf("Close %s ...\n", f.Name)
-			f.Func()
-		}
+	handlers := make(closes, len(closeHandler))
+	copy(handlers, closeHandler)
+	closeLock.Unlock()
+	for _, f := range handlers {
+		fmt.Printf("Close %s ...\n", f.Name)
+		f.Func()
 	}
 	os.Exit(0)
 }
@@ -14,6 +14,12 @@ var (
 	EncryptErr = NewCodeErr(100003, "EncryptionError")
 	DecryptErr = NewCodeErr(100004, "DecryptionError")
 	SignErr    = NewCodeErr(100005, "SignatureError")
+	ExpiredErr = NewCodeErr(100006, "RequestExpired")  // 请求时间戳超出允许的时间窗口
+	ReplayErr  = NewCodeErr(100007, "RequestReplayed") // 时间窗口内重复的 nonce
+
+	IdempotencyConflictErr = NewCodeErr(100008, "RequestInProgress")    // 相同 Idempotency-Key 的请求正在处理
+	IdempotencyMismatchErr = NewCodeErr(100009, "IdempotencyKeyReused") // Idempotency-Key 已用于不同的请求
+	TimeoutErr             = NewCodeErr(100010, "RequestTimeout")       // 请求处理超时
 )
 
 type CodeErr struct {
@@ -41,11 +47,12 @@ func NewCodeErr(code int64, msg string) *CodeErr {
 	}
 }
 
+// GetCodeErr 获取 error 对应的 *CodeErr, 支持下游 grpc 服务返回的错误, 无法解析时返回 SystemErr
 func GetCodeErr(err error) *CodeErr {
 	if err == nil {
 		return nil
 	}
-	if v, ok := err.(*CodeErr); ok {
+	if v, ok := FromError(err); ok {
 		return v
 	}
 	return SystemErr
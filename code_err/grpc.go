@@ -0,0 +1,88 @@
+package code_err
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/grpc/proto/response"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var (
+	grpcCodesLock sync.RWMutex
+	// grpcCodes 业务错误对应的 grpc code, 未注册的业务错误统一为 codes.FailedPrecondition
+	grpcCodes = map[int64]codes.Code{
+		SystemErr.Code:  codes.Internal,
+		ParamsErr.Code:  codes.InvalidArgument,
+		TokenErr.Code:   codes.Unauthenticated,
+		SignErr.Code:    codes.Unauthenticated,
+		ExpiredErr.Code: codes.Unauthenticated,
+		ReplayErr.Code:  codes.Unauthenticated,
+
+		IdempotencyConflictErr.Code: codes.Aborted,
+		IdempotencyMismatchErr.Code: codes.InvalidArgument,
+		TimeoutErr.Code:             codes.DeadlineExceeded,
+	}
+)
+
+// RegisterGrpcCode 注册业务错误对应的 grpc code, 只应该在程序启动时调用
+func RegisterGrpcCode(code int64, grpcCode codes.Code) {
+	grpcCodesLock.Lock()
+	defer grpcCodesLock.Unlock()
+	grpcCodes[code] = grpcCode
+}
+
+func getGrpcCode(code int64) codes.Code {
+	grpcCodesLock.RLock()
+	defer grpcCodesLock.RUnlock()
+	if c, ok := grpcCodes[code]; ok {
+		return c
+	}
+	return codes.FailedPrecondition
+}
+
+// GRPCStatus 实现 grpc status 接口, grpc 服务返回 *CodeErr 时自动转换为带 response.CodeError detail 的 status
+// DebugMsg 可能包含内部错误信息, 只有开启 BaseConfig.Debug 时才传给调用方
+func (err *CodeErr) GRPCStatus() *status.Status {
+	st := status.New(getGrpcCode(err.Code), err.Msg)
+	codeErr := &response.CodeError{
+		Code: err.Code,
+		Msg:  err.Msg,
+	}
+	if isDebug() {
+		codeErr.DebugMsg = err.DebugMsg
+	}
+	detail, e := st.WithDetails(codeErr)
+	if e != nil {
+		return st
+	}
+	return detail
+}
+
+func isDebug() bool {
+	return econfig.GlobalViper != nil && econfig.GlobalViper.GetBool("BaseConfig.Debug")
+}
+
+// FromError 从 error 中解析 *CodeErr
+// 支持 *CodeErr, 被 wrap 的 *CodeErr, 以及下游 grpc 服务返回的带 response.CodeError detail 的 status
+func FromError(err error) (*CodeErr, bool) {
+	if err == nil {
+		return nil, false
+	}
+	var codeErr *CodeErr
+	if errors.As(err, &codeErr) {
+		return codeErr, true
+	}
+	st, ok := status.FromError(err)
+	if !ok {
+		return nil, false
+	}
+	for _, detail := range st.Details() {
+		if v, ok := detail.(*response.CodeError); ok {
+			return New(v.Code, v.Msg, v.DebugMsg), true
+		}
+	}
+	return nil, false
+}
@@ -0,0 +1,56 @@
+package code_err
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/econfig/eviper"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestCodeErr_GRPCStatus(t *testing.T) {
+	st := TokenErr.GRPCStatus()
+	assert.Equal(t, codes.Unauthenticated, st.Code())
+	assert.Equal(t, TokenErr.Msg, st.Message())
+
+	bizErr := New(200001, "UserNotFound", "uid 1")
+	st = status.Convert(bizErr)
+	assert.Equal(t, codes.FailedPrecondition, st.Code())
+
+	RegisterGrpcCode(200001, codes.NotFound)
+	assert.Equal(t, codes.NotFound, status.Code(bizErr))
+}
+
+func TestFromError(t *testing.T) {
+	bizErr := New(200002, "OrderNotFound", "order 1")
+
+	// 经过 grpc 传输后只剩下 status, 默认不传 DebugMsg
+	remoteErr := status.FromProto(bizErr.GRPCStatus().Proto()).Err()
+	codeErr, ok := FromError(remoteErr)
+	assert.True(t, ok)
+	assert.Equal(t, New(200002, "OrderNotFound", ""), codeErr)
+	assert.Equal(t, codeErr, GetCodeErr(remoteErr))
+
+	econfig.GlobalViper = eviper.NewViperFromString(`
+[BaseConfig]
+Debug = true
+`)
+	defer func() { econfig.GlobalViper = nil }()
+	remoteErr = status.FromProto(bizErr.GRPCStatus().Proto()).Err()
+	assert.Equal(t, bizErr, GetCodeErr(remoteErr))
+
+	codeErr, ok = FromError(fmt.Errorf("wrap: %w", bizErr))
+	assert.True(t, ok)
+	assert.Same(t, bizErr, codeErr)
+
+	_, ok = FromError(status.Error(codes.Internal, "internal"))
+	assert.False(t, ok)
+	_, ok = FromError(errors.New("plain"))
+	assert.False(t, ok)
+	assert.Equal(t, SystemErr, GetCodeErr(errors.New("plain")))
+	assert.Nil(t, GetCodeErr(nil))
+}
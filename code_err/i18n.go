@@ -0,0 +1,192 @@
+package code_err
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/weblazy/easy/econfig/eviper"
+)
+
+const (
+	// DefaultI18nKey 配置中错误信息翻译的 key, 格式为 I18n.<language>.<code> = "msg"
+	DefaultI18nKey = "I18n"
+	// SourceLanguage CodeErr.Msg 使用的语言, 没有该语言的翻译时返回 CodeErr.Msg
+	SourceLanguage = "en"
+)
+
+var (
+	messagesLock sync.RWMutex
+	// messages language -> code -> msg, language 统一为小写, 如 zh, zh-tw
+	messages = map[string]map[int64]string{
+		"zh": {
+			SystemErr.Code:  "系统错误",
+			ParamsErr.Code:  "参数错误",
+			TokenErr.Code:   "登录已失效",
+			EncryptErr.Code: "加密失败",
+			DecryptErr.Code: "解密失败",
+			SignErr.Code:    "签名错误",
+			ExpiredErr.Code: "请求已过期",
+			ReplayErr.Code:  "重复的请求",
+
+			IdempotencyConflictErr.Code: "请求正在处理中",
+			IdempotencyMismatchErr.Code: "幂等键已用于其他请求",
+			TimeoutErr.Code:             "请求超时",
+		},
+	}
+	// defaultLanguage 请求没有匹配的语言时使用, 为空时返回 CodeErr.Msg
+	defaultLanguage string
+)
+
+func normalizeLanguage(language string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(language), "_", "-"))
+}
+
+// RegisterMessages 注册语言的错误信息, 相同的 code 会被覆盖, 只应该在程序启动时调用
+func RegisterMessages(language string, msgs map[int64]string) {
+	language = normalizeLanguage(language)
+	messagesLock.Lock()
+	defer messagesLock.Unlock()
+	if messages[language] == nil {
+		messages[language] = make(map[int64]string, len(msgs))
+	}
+	for code, msg := range msgs {
+		messages[language][code] = msg
+	}
+}
+
+// LoadMessages 从配置中加载错误信息, 支持 toml 和 json 等 econfig 支持的格式, key 为空时使用 DefaultI18nKey
+//
+//	[I18n.zh]
+//	100001 = "参数错误"
+func LoadMessages(v *eviper.Viper, key string) error {
+	if key == "" {
+		key = DefaultI18nKey
+	}
+	for language, value := range v.GetStringMap(key) {
+		items, ok := value.(map[string]interface{})
+		if !ok {
+			return fmt.Errorf("i18n %s.%s is not a table", key, language)
+		}
+		msgs := make(map[int64]string, len(items))
+		for code, msg := range items {
+			c, err := strconv.ParseInt(code, 10, 64)
+			if err != nil {
+				return fmt.Errorf("i18n %s.%s: invalid code %s", key, language, code)
+			}
+			msgs[c] = fmt.Sprint(msg)
+		}
+		RegisterMessages(language, msgs)
+	}
+	return nil
+}
+
+// SetDefaultLanguage 设置没有匹配的语言时使用的语言
+func SetDefaultLanguage(language string) {
+	messagesLock.Lock()
+	defer messagesLock.Unlock()
+	defaultLanguage = normalizeLanguage(language)
+}
+
+// fallbacks 语言及其主语言, 如 zh-hant-tw, zh-hant, zh
+func fallbacks(language string) []string {
+	var languages []string
+	for language != "" {
+		languages = append(languages, language)
+		i := strings.LastIndex(language, "-")
+		if i < 0 {
+			break
+		}
+		language = language[:i]
+	}
+	return languages
+}
+
+// lookup 依次查找语言和主语言, 匹配到 SourceLanguage 时返回 CodeErr.Msg
+func lookup(language string, code int64) (string, bool) {
+	for _, l := range fallbacks(language) {
+		if msg, ok := messages[l][code]; ok {
+			return msg, true
+		}
+		if l == SourceLanguage {
+			return "", true
+		}
+	}
+	return "", false
+}
+
+// Translate 返回指定语言的错误信息, 依次使用 language, 主语言, 默认语言, 都没有时返回原来的 CodeErr
+func (err *CodeErr) Translate(language string) *CodeErr {
+	if err == nil {
+		return nil
+	}
+	messagesLock.RLock()
+	defer messagesLock.RUnlock()
+	msg, ok := lookup(normalizeLanguage(language), err.Code)
+	if !ok {
+		msg, ok = lookup(defaultLanguage, err.Code)
+	}
+	if !ok || msg == "" || msg == err.Msg {
+		return err
+	}
+	return New(err.Code, msg, err.DebugMsg)
+}
+
+// supported 是否有该语言或主语言的错误信息
+func supported(language string) bool {
+	for _, l := range fallbacks(language) {
+		if _, ok := messages[l]; ok || l == SourceLanguage {
+			return true
+		}
+	}
+	return false
+}
+
+// NegotiateLanguage 按顺序解析 X-Language, Accept-Language 等 header, 返回第一个有错误信息的语言, 没有时返回空
+// 每个 header 支持 Accept-Language 格式, 如 zh-CN,zh;q=0.9,en;q=0.8
+func NegotiateLanguage(headers ...string) string {
+	messagesLock.RLock()
+	defer messagesLock.RUnlock()
+	for _, header := range headers {
+		for _, language := range parseAcceptLanguage(header) {
+			if supported(language) {
+				return language
+			}
+		}
+	}
+	return ""
+}
+
+func parseAcceptLanguage(header string) []string {
+	type item struct {
+		language string
+		q        float64
+	}
+	var items []item
+	for _, part := range strings.Split(header, ",") {
+		fields := strings.Split(part, ";")
+		language := normalizeLanguage(fields[0])
+		if language == "" || language == "*" {
+			continue
+		}
+		q := 1.0
+		for _, param := range fields[1:] {
+			if v := strings.TrimSpace(param); strings.HasPrefix(v, "q=") {
+				if f, err := strconv.ParseFloat(v[2:], 64); err == nil {
+					q = f
+				}
+			}
+		}
+		if q > 0 {
+			items = append(items, item{language: language, q: q})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].q > items[j].q })
+	languages := make([]string, len(items))
+	for i := range items {
+		languages[i] = items[i].language
+	}
+	return languages
+}
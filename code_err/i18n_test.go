@@ -0,0 +1,47 @@
+package code_err
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/econfig/eviper"
+)
+
+func TestTranslate(t *testing.T) {
+	assert.Equal(t, "参数错误", ParamsErr.Translate("zh-CN").Msg)
+	assert.Equal(t, "参数错误", ParamsErr.Translate("zh_Hans_CN").Msg)
+	assert.Equal(t, "ParamsError", ParamsErr.Translate("en-US").Msg)
+	assert.Equal(t, "ParamsError", ParamsErr.Translate("").Msg)
+	// 不修改原来的错误
+	assert.Equal(t, "ParamsError", ParamsErr.Msg)
+	assert.Equal(t, "debug", ParamsErr.WithDebugMsg("debug").Translate("zh").DebugMsg)
+
+	err := LoadMessages(eviper.NewViperFromString(`
+[I18n.th]
+100001 = "พารามิเตอร์ไม่ถูกต้อง"
+[I18n.zh-TW]
+100001 = "參數錯誤"
+`), "")
+	assert.Nil(t, err)
+	assert.Equal(t, "พารามิเตอร์ไม่ถูกต้อง", ParamsErr.Translate("th").Msg)
+	assert.Equal(t, "參數錯誤", ParamsErr.Translate("zh-TW").Msg)
+	// 缺少翻译时使用主语言
+	assert.Equal(t, "登录已失效", TokenErr.Translate("zh-TW").Msg)
+	// 缺少翻译时返回原来的信息
+	assert.Equal(t, "InvalidToken", TokenErr.Translate("th").Msg)
+
+	// 默认语言, 明确请求英文时不使用默认语言
+	SetDefaultLanguage("zh")
+	defer SetDefaultLanguage("")
+	assert.Equal(t, "参数错误", ParamsErr.Translate("fr").Msg)
+	assert.Equal(t, "参数错误", ParamsErr.Translate("").Msg)
+	assert.Equal(t, "ParamsError", ParamsErr.Translate("en").Msg)
+}
+
+func TestNegotiateLanguage(t *testing.T) {
+	assert.Equal(t, "zh-cn", NegotiateLanguage("zh-CN", "en"))
+	assert.Equal(t, "en", NegotiateLanguage("", "en;q=0.8,fr,*;q=0.5"))
+	assert.Equal(t, "zh", NegotiateLanguage("", "fr-FR,en;q=0.8,zh;q=0.9"))
+	assert.Equal(t, "en-us", NegotiateLanguage("fr", "en-US,zh;q=0"))
+	assert.Equal(t, "", NegotiateLanguage("fr", "de"))
+}
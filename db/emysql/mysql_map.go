@@ -4,12 +4,17 @@ import (
 	"context"
 	"sync"
 
+	"github.com/weblazy/easy/closes"
 	"github.com/weblazy/easy/db/emysql/emysql_config"
+	"github.com/weblazy/easy/ecomponent"
 	"github.com/weblazy/easy/econfig"
 	"gorm.io/gorm"
 )
 
-var MysqlMap sync.Map
+var (
+	MysqlMap sync.Map
+	mysqlMu  sync.Mutex
+)
 
 // GetMysql return a MysqlClient
 func GetMysql(ctx context.Context, dbName string) *gorm.DB {
@@ -23,6 +28,11 @@ func GetMysql(ctx context.Context, dbName string) *gorm.DB {
 
 // getMysql return a *gorm.DB
 func getMysql(ctx context.Context, dbName string) *gorm.DB {
+	if v, ok := MysqlMap.Load(dbName); ok {
+		return v.(*MysqlClient).DB.WithContext(ctx)
+	}
+	mysqlMu.Lock()
+	defer mysqlMu.Unlock()
 	if v, ok := MysqlMap.Load(dbName); ok {
 		return v.(*MysqlClient).DB.WithContext(ctx)
 	}
@@ -34,5 +44,11 @@ func getMysql(ctx context.Context, dbName string) *gorm.DB {
 	}
 
 	MysqlMap.Store(dbName, mysqlClient)
+	ecomponent.Register(ecomponent.KindMysql, dbName, closes.GormPriority, func() {
+		MysqlMap.Delete(dbName)
+		if db, err := mysqlClient.DB.DB(); err == nil {
+			db.Close()
+		}
+	})
 	return mysqlClient.DB.WithContext(ctx)
 }
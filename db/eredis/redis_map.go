@@ -3,20 +3,40 @@ package eredis
 import (
 	"sync"
 
+	"github.com/weblazy/easy/closes"
 	"github.com/weblazy/easy/db/eredis/eredis_config"
+	"github.com/weblazy/easy/ecomponent"
 	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/elog"
 )
 
-var RedisMap sync.Map
+var (
+	RedisMap sync.Map
+	redisMu  sync.Mutex
+)
 
 // GetRedis return a RedisClient
 func GetRedis(dbName string) *RedisClient {
+	if v, ok := RedisMap.Load(dbName); ok {
+		return v.(*RedisClient)
+	}
+	redisMu.Lock()
+	defer redisMu.Unlock()
 	if v, ok := RedisMap.Load(dbName); ok {
 		return v.(*RedisClient)
 	}
 	conf := eredis_config.DefaultConfig()
 	econfig.GlobalViper.UnmarshalKey(dbName, conf)
 	redisClient := NewRedisClient(conf)
+	if redisClient == nil {
+		return nil
+	}
 	RedisMap.Store(dbName, redisClient)
+	ecomponent.Register(ecomponent.KindRedis, dbName, closes.RedisPriority, func() {
+		RedisMap.Delete(dbName)
+		if err := redisClient.Close(); err != nil {
+			elog.ErrorCtx(emptyCtx, "close redis", elog.FieldName(dbName), elog.FieldError(err))
+		}
+	})
 	return redisClient
 }
@@ -0,0 +1,201 @@
+package ebreaker
+
+import (
+	"errors"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Google SRE 自适应限流熔断 https://sre.google/sre-book/handling-overload/#eq2101
+// 拒绝概率 = max(0, (requests - K * accepts) / (requests + 1))
+
+// ErrNotAllowed 熔断器打开时拒绝请求返回的错误
+var ErrNotAllowed = errors.New("circuit breaker is open")
+
+// State 熔断器状态
+type State int
+
+const (
+	StateClosed State = iota // 正常放行
+	StateOpen                // 按照拒绝概率丢弃请求
+)
+
+var (
+	// BreakerStateGauge 熔断器状态, 0 为关闭, 1 为打开
+	BreakerStateGauge = prometheus.NewGaugeVec(
+		prometheus.GaugeOpts{
+			Name: "circuit_breaker_state",
+			Help: "Circuit breaker state, 0 closed, 1 open.",
+		}, []string{"type", "name", "method"})
+)
+
+func init() {
+	prometheus.MustRegister(BreakerStateGauge)
+}
+
+// Config 熔断配置
+type Config struct {
+	Window  time.Duration // 统计窗口, 默认 10s
+	Buckets int           // 窗口分桶数, 默认 40
+	K       float64       // 倍率, 越小越容易熔断, 默认 1.5
+	Request int64         // 窗口内请求数小于该值时不熔断, 默认 100
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Window:  time.Second * 10,
+		Buckets: 40,
+		K:       1.5,
+		Request: 100,
+	}
+}
+
+type bucket struct {
+	accepts  int64
+	requests int64
+}
+
+// Breaker 单个目标的熔断器
+type Breaker struct {
+	config   Config
+	mu       sync.Mutex
+	buckets  []bucket
+	offset   int
+	lastTime time.Time
+	state    State
+	onChange func(state State)
+}
+
+// NewBreaker 创建熔断器, onChange 在状态变化时调用, 可以为空
+func NewBreaker(config *Config, onChange func(state State)) *Breaker {
+	c := *DefaultConfig()
+	if config != nil {
+		if config.Window > 0 {
+			c.Window = config.Window
+		}
+		if config.Buckets > 0 {
+			c.Buckets = config.Buckets
+		}
+		if config.K > 0 {
+			c.K = config.K
+		}
+		if config.Request > 0 {
+			c.Request = config.Request
+		}
+	}
+	return &Breaker{
+		config:   c,
+		buckets:  make([]bucket, c.Buckets),
+		lastTime: time.Now(),
+		onChange: onChange,
+	}
+}
+
+// Allow 判断请求是否放行, 放行后需要调用 Mark 记录结果, 拒绝时返回 ErrNotAllowed
+func (b *Breaker) Allow() error {
+	b.mu.Lock()
+	accepts, requests := b.sum()
+	ratio := math.Max(0, (float64(requests)-b.config.K*float64(accepts))/float64(requests+1))
+	state := StateClosed
+	if requests >= b.config.Request && ratio > 0 {
+		state = StateOpen
+	}
+	drop := state == StateOpen && rand.Float64() < ratio
+	if drop {
+		// 被拒绝的请求也计入请求数, 后端恢复前拒绝概率持续增加
+		b.add(0, 1)
+	}
+	changed := state != b.state
+	b.state = state
+	b.mu.Unlock()
+
+	if changed && b.onChange != nil {
+		b.onChange(state)
+	}
+	if drop {
+		return ErrNotAllowed
+	}
+	return nil
+}
+
+// Mark 记录请求结果
+func (b *Breaker) Mark(success bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if success {
+		b.add(1, 1)
+		return
+	}
+	b.add(0, 1)
+}
+
+// State 当前状态
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.state
+}
+
+// rotate 按时间移动窗口, 清空过期的桶
+func (b *Breaker) rotate() {
+	interval := b.config.Window / time.Duration(b.config.Buckets)
+	span := int(time.Since(b.lastTime) / interval)
+	if span <= 0 {
+		return
+	}
+	if span > b.config.Buckets {
+		span = b.config.Buckets
+	}
+	for i := 1; i <= span; i++ {
+		b.buckets[(b.offset+i)%b.config.Buckets] = bucket{}
+	}
+	b.offset = (b.offset + span) % b.config.Buckets
+	b.lastTime = b.lastTime.Add(time.Duration(span) * interval)
+	if time.Since(b.lastTime) >= interval {
+		b.lastTime = time.Now()
+	}
+}
+
+func (b *Breaker) add(accepts, requests int64) {
+	b.rotate()
+	b.buckets[b.offset].accepts += accepts
+	b.buckets[b.offset].requests += requests
+}
+
+func (b *Breaker) sum() (accepts, requests int64) {
+	b.rotate()
+	for _, v := range b.buckets {
+		accepts += v.accepts
+		requests += v.requests
+	}
+	return accepts, requests
+}
+
+// Group 按方法区分的熔断器集合, 状态变化记录在 BreakerStateGauge
+type Group struct {
+	typ      string
+	name     string
+	config   *Config
+	breakers sync.Map
+}
+
+// NewGroup 创建熔断器集合, typ 为 grpc 或 http, name 为目标服务
+func NewGroup(typ, name string, config *Config) *Group {
+	return &Group{typ: typ, name: name, config: config}
+}
+
+// Get 获取方法对应的熔断器, 不存在时创建
+func (g *Group) Get(method string) *Breaker {
+	if b, ok := g.breakers.Load(method); ok {
+		return b.(*Breaker)
+	}
+	b, _ := g.breakers.LoadOrStore(method, NewBreaker(g.config, func(state State) {
+		BreakerStateGauge.WithLabelValues(g.typ, g.name, method).Set(float64(state))
+	}))
+	return b.(*Breaker)
+}
@@ -0,0 +1,61 @@
+package ebreaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBreaker(t *testing.T) {
+	b := NewBreaker(&Config{Window: 100 * time.Millisecond, Buckets: 10, K: 1.5, Request: 10}, nil)
+
+	// 请求数不足时不熔断
+	for i := 0; i < 9; i++ {
+		assert.Nil(t, b.Allow())
+		b.Mark(false)
+	}
+	assert.Equal(t, StateClosed, b.State())
+
+	// 全部失败后大部分请求被拒绝
+	for i := 0; i < 100; i++ {
+		if b.Allow() == nil {
+			b.Mark(false)
+		}
+	}
+	assert.Equal(t, StateOpen, b.State())
+	var dropped int
+	for i := 0; i < 100; i++ {
+		if b.Allow() == ErrNotAllowed {
+			dropped++
+		}
+	}
+	assert.Greater(t, dropped, 80)
+
+	// 窗口过期后恢复
+	time.Sleep(120 * time.Millisecond)
+	assert.Nil(t, b.Allow())
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestBreaker_success(t *testing.T) {
+	b := NewBreaker(&Config{Request: 10}, nil)
+	for i := 0; i < 1000; i++ {
+		assert.Nil(t, b.Allow())
+		b.Mark(true)
+	}
+	assert.Equal(t, StateClosed, b.State())
+}
+
+func TestGroup(t *testing.T) {
+	g := NewGroup("grpc", "user", &Config{Request: 1})
+	b := g.Get("/user.UserService/GetUserInfo")
+	assert.Equal(t, b, g.Get("/user.UserService/GetUserInfo"))
+	for i := 0; i < 100; i++ {
+		if b.Allow() == nil {
+			b.Mark(false)
+		}
+	}
+	assert.Equal(t, float64(StateOpen), testutil.ToFloat64(BreakerStateGauge.WithLabelValues("grpc", "user", "/user.UserService/GetUserInfo")))
+}
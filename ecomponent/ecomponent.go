@@ -0,0 +1,81 @@
+package ecomponent
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/weblazy/easy/closes"
+)
+
+// 组件类型
+const (
+	KindGrpcClient         = "grpc_client"
+	KindHttpClient         = "http_client"
+	KindKafkaProducer      = "kafka_producer"
+	KindKafkaConsumerGroup = "kafka_consumer_group"
+	KindMysql              = "mysql"
+	KindRedis              = "redis"
+	KindGrpcServer         = "grpc_server"
+	KindHttpServer         = "http_server"
+)
+
+// Component 已创建的组件, 用于诊断
+type Component struct {
+	Kind      string    `json:"kind"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+}
+
+var (
+	mu         sync.Mutex
+	components = make(map[string]Component)
+)
+
+func key(kind, name string) string {
+	return kind + "/" + name
+}
+
+// Register 记录组件, 并在程序退出时按照 priority 调用 closeFn, closeFn 为空时不注册关闭方法
+func Register(kind, name string, priority int, closeFn func()) {
+	mu.Lock()
+	components[key(kind, name)] = Component{Kind: kind, Name: name, CreatedAt: time.Now()}
+	mu.Unlock()
+
+	if closeFn == nil {
+		return
+	}
+	closes.AddShutdown(closes.ModuleClose{
+		Name:     kind + " " + name + " Close",
+		Priority: priority,
+		Func: func() {
+			closeFn()
+			Unregister(kind, name)
+		},
+	})
+}
+
+// Unregister 删除组件记录
+func Unregister(kind, name string) {
+	mu.Lock()
+	defer mu.Unlock()
+	delete(components, key(kind, name))
+}
+
+// List 当前存活的组件, 按照类型和名称排序
+func List() []Component {
+	mu.Lock()
+	list := make([]Component, 0, len(components))
+	for _, c := range components {
+		list = append(list, c)
+	}
+	mu.Unlock()
+
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].Kind != list[j].Kind {
+			return list[i].Kind < list[j].Kind
+		}
+		return list[i].Name < list[j].Name
+	})
+	return list
+}
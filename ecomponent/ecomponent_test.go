@@ -0,0 +1,24 @@
+package ecomponent
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegister(t *testing.T) {
+	Register(KindRedis, "redis.b", 0, nil)
+	Register(KindGrpcClient, "user", 0, nil)
+	Register(KindRedis, "redis.a", 0, nil)
+
+	list := List()
+	names := make([]string, 0, len(list))
+	for _, c := range list {
+		names = append(names, c.Kind+"/"+c.Name)
+		assert.False(t, c.CreatedAt.IsZero())
+	}
+	assert.Equal(t, []string{"grpc_client/user", "redis/redis.a", "redis/redis.b"}, names)
+
+	Unregister(KindRedis, "redis.a")
+	assert.Len(t, List(), 2)
+}
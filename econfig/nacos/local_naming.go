@@ -0,0 +1,236 @@
+package nacos
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/util"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+// LocalNaming 本地服务发现, 实例保存在内存中, 用于单测
+type LocalNaming struct {
+	mu          sync.RWMutex
+	instances   map[string][]model.Instance
+	subscribers map[string][]*vo.SubscribeParam
+}
+
+// SetLocalNaming 注入本地服务发现
+func SetLocalNaming() *LocalNaming {
+	localNaming := NewLocalNaming()
+	SetNamingClient(localNaming)
+	return localNaming
+}
+
+func NewLocalNaming() *LocalNaming {
+	return &LocalNaming{
+		instances:   make(map[string][]model.Instance),
+		subscribers: make(map[string][]*vo.SubscribeParam),
+	}
+}
+
+var _ naming_client.INamingClient = (*LocalNaming)(nil)
+
+func namingKey(serviceName, groupName string) string {
+	if groupName == "" {
+		groupName = constant.DEFAULT_GROUP
+	}
+	return util.GetGroupName(serviceName, groupName)
+}
+
+func instanceId(ip string, port uint64) string {
+	return fmt.Sprintf("%s:%d", ip, port)
+}
+
+// SetInstances 设置服务的全部实例, 并通知订阅者
+func (l *LocalNaming) SetInstances(serviceName, groupName string, instances ...model.Instance) {
+	key := namingKey(serviceName, groupName)
+	l.mu.Lock()
+	for i := range instances {
+		if instances[i].InstanceId == "" {
+			instances[i].InstanceId = instanceId(instances[i].Ip, instances[i].Port)
+		}
+		if instances[i].ServiceName == "" {
+			instances[i].ServiceName = key
+		}
+	}
+	l.instances[key] = instances
+	l.mu.Unlock()
+	l.notify(key)
+}
+
+func (l *LocalNaming) notify(key string) {
+	l.mu.RLock()
+	subscribers := append([]*vo.SubscribeParam(nil), l.subscribers[key]...)
+	l.mu.RUnlock()
+	for _, param := range subscribers {
+		services := make([]model.SubscribeService, 0)
+		for _, instance := range l.selectInstances(key, param.Clusters) {
+			services = append(services, model.SubscribeService{
+				ClusterName: instance.ClusterName,
+				Enable:      instance.Enable,
+				InstanceId:  instance.InstanceId,
+				Ip:          instance.Ip,
+				Metadata:    instance.Metadata,
+				Port:        instance.Port,
+				ServiceName: instance.ServiceName,
+				Valid:       instance.Valid,
+				Weight:      instance.Weight,
+			})
+		}
+		param.SubscribeCallback(services, nil)
+	}
+}
+
+func (l *LocalNaming) selectInstances(key string, clusters []string) []model.Instance {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	instances := make([]model.Instance, 0, len(l.instances[key]))
+	for _, instance := range l.instances[key] {
+		if len(clusters) > 0 && !contains(clusters, instance.ClusterName) {
+			continue
+		}
+		instances = append(instances, instance)
+	}
+	return instances
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *LocalNaming) RegisterInstance(param vo.RegisterInstanceParam) (bool, error) {
+	key := namingKey(param.ServiceName, param.GroupName)
+	id := instanceId(param.Ip, param.Port)
+	clusterName := param.ClusterName
+	if clusterName == "" {
+		clusterName = "DEFAULT"
+	}
+	l.mu.Lock()
+	instances := make([]model.Instance, 0, len(l.instances[key])+1)
+	for _, instance := range l.instances[key] {
+		if instance.InstanceId != id {
+			instances = append(instances, instance)
+		}
+	}
+	l.instances[key] = append(instances, model.Instance{
+		Valid:       true,
+		InstanceId:  id,
+		Port:        param.Port,
+		Ip:          param.Ip,
+		Weight:      param.Weight,
+		Metadata:    param.Metadata,
+		ClusterName: clusterName,
+		ServiceName: key,
+		Enable:      param.Enable,
+		Healthy:     param.Healthy,
+		Ephemeral:   param.Ephemeral,
+	})
+	l.mu.Unlock()
+	l.notify(key)
+	return true, nil
+}
+
+func (l *LocalNaming) DeregisterInstance(param vo.DeregisterInstanceParam) (bool, error) {
+	key := namingKey(param.ServiceName, param.GroupName)
+	id := instanceId(param.Ip, param.Port)
+	l.mu.Lock()
+	instances := make([]model.Instance, 0, len(l.instances[key]))
+	for _, instance := range l.instances[key] {
+		if instance.InstanceId != id {
+			instances = append(instances, instance)
+		}
+	}
+	l.instances[key] = instances
+	l.mu.Unlock()
+	l.notify(key)
+	return true, nil
+}
+
+func (l *LocalNaming) UpdateInstance(param vo.UpdateInstanceParam) (bool, error) {
+	key := namingKey(param.ServiceName, param.GroupName)
+	id := instanceId(param.Ip, param.Port)
+	l.mu.Lock()
+	for i, instance := range l.instances[key] {
+		if instance.InstanceId == id {
+			l.instances[key][i].Weight = param.Weight
+			l.instances[key][i].Enable = param.Enable
+			l.instances[key][i].Metadata = param.Metadata
+		}
+	}
+	l.mu.Unlock()
+	l.notify(key)
+	return true, nil
+}
+
+func (l *LocalNaming) GetService(param vo.GetServiceParam) (model.Service, error) {
+	key := namingKey(param.ServiceName, param.GroupName)
+	return model.Service{
+		Name:  key,
+		Hosts: l.selectInstances(key, param.Clusters),
+	}, nil
+}
+
+func (l *LocalNaming) SelectAllInstances(param vo.SelectAllInstancesParam) ([]model.Instance, error) {
+	return l.selectInstances(namingKey(param.ServiceName, param.GroupName), param.Clusters), nil
+}
+
+func (l *LocalNaming) SelectInstances(param vo.SelectInstancesParam) ([]model.Instance, error) {
+	instances := make([]model.Instance, 0)
+	for _, instance := range l.selectInstances(namingKey(param.ServiceName, param.GroupName), param.Clusters) {
+		if instance.Enable && instance.Weight > 0 && (instance.Healthy || !param.HealthyOnly) {
+			instances = append(instances, instance)
+		}
+	}
+	return instances, nil
+}
+
+func (l *LocalNaming) SelectOneHealthyInstance(param vo.SelectOneHealthInstanceParam) (*model.Instance, error) {
+	for _, instance := range l.selectInstances(namingKey(param.ServiceName, param.GroupName), param.Clusters) {
+		if instance.Enable && instance.Healthy && instance.Weight > 0 {
+			return &instance, nil
+		}
+	}
+	return nil, fmt.Errorf("no healthy instance of %s", param.ServiceName)
+}
+
+func (l *LocalNaming) Subscribe(param *vo.SubscribeParam) error {
+	key := namingKey(param.ServiceName, param.GroupName)
+	l.mu.Lock()
+	l.subscribers[key] = append(l.subscribers[key], param)
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *LocalNaming) Unsubscribe(param *vo.SubscribeParam) error {
+	key := namingKey(param.ServiceName, param.GroupName)
+	l.mu.Lock()
+	subscribers := make([]*vo.SubscribeParam, 0, len(l.subscribers[key]))
+	for _, v := range l.subscribers[key] {
+		if v != param {
+			subscribers = append(subscribers, v)
+		}
+	}
+	l.subscribers[key] = subscribers
+	l.mu.Unlock()
+	return nil
+}
+
+func (l *LocalNaming) GetAllServicesInfo(param vo.GetAllServiceInfoParam) (model.ServiceList, error) {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	list := model.ServiceList{}
+	for key := range l.instances {
+		list.Doms = append(list.Doms, key)
+	}
+	list.Count = int64(len(list.Doms))
+	return list, nil
+}
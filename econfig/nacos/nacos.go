@@ -7,6 +7,7 @@ import (
 
 	"github.com/nacos-group/nacos-sdk-go/clients"
 	"github.com/nacos-group/nacos-sdk-go/clients/config_client"
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
 	"github.com/nacos-group/nacos-sdk-go/common/constant"
 	"github.com/nacos-group/nacos-sdk-go/vo"
 	"github.com/spf13/cast"
@@ -15,6 +16,7 @@ import (
 
 type nacos struct {
 	icc   config_client.IConfigClient
+	inc   naming_client.INamingClient
 	vt    *ViperToml
 	local bool
 }
@@ -0,0 +1,41 @@
+package nacos
+
+import (
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/clients"
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/common/constant"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+)
+
+// namingMu 保护 nacosHarder.inc, 服务注册和 resolver 可能在不同 goroutine 中获取客户端
+var namingMu sync.RWMutex
+
+// NewNacosNaming 初始化 Nacos 服务发现客户端, 与配置客户端使用相同的连接参数
+func NewNacosNaming(ccConfig *constant.ClientConfig, scConfigs ...constant.ServerConfig) error {
+	defaultClientConfig(ccConfig)
+	namingClient, err := clients.NewNamingClient(vo.NacosClientParam{
+		ClientConfig:  ccConfig,
+		ServerConfigs: scConfigs,
+	})
+	if err != nil {
+		return err
+	}
+	SetNamingClient(namingClient)
+	return nil
+}
+
+// SetNamingClient 注入服务发现客户端, 单测时可以注入 LocalNaming
+func SetNamingClient(inc naming_client.INamingClient) {
+	namingMu.Lock()
+	defer namingMu.Unlock()
+	nacosHarder.inc = inc
+}
+
+// GetNamingClient 获取服务发现客户端, 未初始化时返回 nil
+func GetNamingClient() naming_client.INamingClient {
+	namingMu.RLock()
+	defer namingMu.RUnlock()
+	return nacosHarder.inc
+}
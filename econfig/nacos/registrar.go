@@ -0,0 +1,152 @@
+package nacos
+
+import (
+	"errors"
+	"net"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"github.com/weblazy/easy/elog"
+	"go.uber.org/zap"
+)
+
+// 注册实例时写入的 metadata key
+const (
+	MetadataVersion = "version"
+	MetadataGray    = "gray"
+)
+
+var ErrNoLocalIP = errors.New("nacos registrar can not find local ip")
+
+// RegistrarConfig 服务注册配置
+type RegistrarConfig struct {
+	ServiceName string            // 服务名, 默认使用 server 的 Name
+	GroupName   string            // 服务分组, 默认 DEFAULT_GROUP
+	ClusterName string            // 集群, 默认 DEFAULT
+	Ip          string            // 注册的 ip, 默认使用本机内网 ip
+	Port        uint64            // 注册的端口, 默认使用 server 监听的端口
+	Weight      float64           // 权重, 默认 1, grpc_client 使用 round_robin 不按权重分配流量, 权重为 0 的实例不会被使用
+	Version     string            // 版本, 写入 metadata version
+	GrayTag     string            // 灰度标识, 写入 metadata gray
+	Metadata    map[string]string // 其他 metadata
+}
+
+// Registrar 将服务实例注册到 nacos
+// 注册的是临时实例, nacos 客户端会按照 BeatInterval 自动发送心跳, 进程退出后实例自动过期
+type Registrar struct {
+	config     RegistrarConfig
+	client     naming_client.INamingClient
+	mu         sync.Mutex
+	registered bool
+}
+
+// NewRegistrar 创建服务注册, client 为空时使用 GetNamingClient()
+func NewRegistrar(config RegistrarConfig, client naming_client.INamingClient) *Registrar {
+	if client == nil {
+		client = GetNamingClient()
+	}
+	if config.Weight <= 0 {
+		config.Weight = 1
+	}
+	return &Registrar{config: config, client: client}
+}
+
+// Register 注册实例, 需要在 listener 可以接收连接之后调用
+func (r *Registrar) Register() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.client == nil {
+		return errors.New("nacos naming client is nil, init it by nacos.NewNacosNaming")
+	}
+	if r.config.Ip == "" {
+		ip, err := LocalIP()
+		if err != nil {
+			return err
+		}
+		r.config.Ip = ip
+	}
+	_, err := r.client.RegisterInstance(vo.RegisterInstanceParam{
+		Ip:          r.config.Ip,
+		Port:        r.config.Port,
+		Weight:      r.config.Weight,
+		Enable:      true,
+		Healthy:     true,
+		Metadata:    r.metadata(),
+		ClusterName: r.config.ClusterName,
+		ServiceName: r.config.ServiceName,
+		GroupName:   r.config.GroupName,
+		Ephemeral:   true,
+	})
+	if err != nil {
+		elog.ErrorCtx(emptyCtx, "nacos register instance err", r.fields(elog.FieldError(err))...)
+		return err
+	}
+	r.registered = true
+	elog.InfoCtx(emptyCtx, "nacos register instance success", r.fields()...)
+	return nil
+}
+
+// Deregister 注销实例, 未注册或已注销时直接返回
+func (r *Registrar) Deregister() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if !r.registered {
+		return nil
+	}
+	_, err := r.client.DeregisterInstance(vo.DeregisterInstanceParam{
+		Ip:          r.config.Ip,
+		Port:        r.config.Port,
+		Cluster:     r.config.ClusterName,
+		ServiceName: r.config.ServiceName,
+		GroupName:   r.config.GroupName,
+		Ephemeral:   true,
+	})
+	if err != nil {
+		elog.ErrorCtx(emptyCtx, "nacos deregister instance err", r.fields(elog.FieldError(err))...)
+		return err
+	}
+	r.registered = false
+	elog.InfoCtx(emptyCtx, "nacos deregister instance success", r.fields()...)
+	return nil
+}
+
+func (r *Registrar) metadata() map[string]string {
+	metadata := make(map[string]string, len(r.config.Metadata)+2)
+	for k, v := range r.config.Metadata {
+		metadata[k] = v
+	}
+	if r.config.Version != "" {
+		metadata[MetadataVersion] = r.config.Version
+	}
+	if r.config.GrayTag != "" {
+		metadata[MetadataGray] = r.config.GrayTag
+	}
+	return metadata
+}
+
+func (r *Registrar) fields(fields ...zap.Field) []zap.Field {
+	return append([]zap.Field{
+		zap.String("service", r.config.ServiceName),
+		zap.String("ip", r.config.Ip),
+		zap.Uint64("port", r.config.Port),
+	}, fields...)
+}
+
+// LocalIP 获取本机第一个非回环的 ipv4 地址
+func LocalIP() (string, error) {
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return "", err
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok || ipNet.IP.IsLoopback() {
+			continue
+		}
+		if ip := ipNet.IP.To4(); ip != nil {
+			return ip.String(), nil
+		}
+	}
+	return "", ErrNoLocalIP
+}
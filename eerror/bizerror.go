@@ -3,6 +3,7 @@ package eerror
 import (
 	"strconv"
 
+	"github.com/weblazy/easy/code_err"
 	"github.com/weblazy/easy/set"
 )
 
@@ -50,15 +51,14 @@ func ExtractBizCode(successCodes []string) func(resp interface{}, err error) (st
 	}
 
 	return func(resp interface{}, err error) (string, bool) {
-		// if err != nil {
-		// commonErr, ok := FromErrorIsDetail(err)
-		// // 1. rich error
-		// if ok {
-		// 	return commonErr.BizCode, true
-		// }
-		// non biz error
-		// 	return "", false
-		// }
+		if err != nil {
+			// 1. rich error
+			if codeErr, ok := code_err.FromError(err); ok {
+				return replacer(strconv.FormatInt(codeErr.Code, 10)), true
+			}
+			// non biz error
+			return "", false
+		}
 
 		// // 2. 内嵌 commonError
 		// if cer, ok := resp.(commonErrResp); ok {
@@ -99,7 +99,10 @@ func (s *ConsumerGroup) Start() {
 func (s *ConsumerGroup) Close() error {
 	if s.cg != nil {
 		elog.InfoCtx(context.Background(), "consumer group close")
-		s.cancel()
+		// 未调用 Start 时没有 cancel
+		if s.cancel != nil {
+			s.cancel()
+		}
 		return s.cg.Close()
 	}
 
@@ -0,0 +1,90 @@
+package ekafka
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/elog"
+)
+
+var (
+	ProducerMap      sync.Map
+	ConsumerGroupMap sync.Map
+	kafkaMu          sync.Mutex
+)
+
+func loadConfig(name string) *Config {
+	conf := DefaultConfig()
+	econfig.GlobalViper.UnmarshalKey(name, conf)
+	return conf
+}
+
+// GetKafkaProducer 按照配置 key 获取 producer, 不存在时从 econfig.GlobalViper 读取配置创建
+func GetKafkaProducer(name string) (*Producer, error) {
+	if v, ok := ProducerMap.Load(name); ok {
+		return v.(*Producer), nil
+	}
+	kafkaMu.Lock()
+	defer kafkaMu.Unlock()
+	if v, ok := ProducerMap.Load(name); ok {
+		return v.(*Producer), nil
+	}
+
+	producer, err := NewProducer(name, loadConfig(name))
+	if err != nil {
+		elog.ErrorCtx(context.Background(), "new kafka producer", elog.FieldName(name), elog.FieldError(err))
+		return nil, err
+	}
+
+	ProducerMap.Store(name, producer)
+	ecomponent.Register(ecomponent.KindKafkaProducer, name, closes.MQPriority, func() {
+		ProducerMap.Delete(name)
+		if err := producer.Close(); err != nil {
+			elog.ErrorCtx(context.Background(), "close kafka producer", elog.FieldName(name), elog.FieldError(err))
+		}
+	})
+	return producer, nil
+}
+
+// GetKafkaConsumerGroup 按照配置 key 获取 consumer group, group 为配置中 ConsumerGroupConfigs 的 key
+// 创建后需要调用 SetHandler 和 Start 开始消费
+func GetKafkaConsumerGroup(name, group string) (*ConsumerGroup, error) {
+	key := name + "." + group
+	if v, ok := ConsumerGroupMap.Load(key); ok {
+		return v.(*ConsumerGroup), nil
+	}
+	kafkaMu.Lock()
+	defer kafkaMu.Unlock()
+	if v, ok := ConsumerGroupMap.Load(key); ok {
+		return v.(*ConsumerGroup), nil
+	}
+
+	conf := loadConfig(name)
+	// viper 读取的 map key 为小写
+	groupConfig, ok := conf.ConsumerGroupConfigs[group]
+	if !ok {
+		groupConfig, ok = conf.ConsumerGroupConfigs[strings.ToLower(group)]
+	}
+	if !ok {
+		return nil, fmt.Errorf("kafka consumer group %s not found in %s", group, name)
+	}
+	consumerGroup, err := NewConsumerGroup(name, conf, &groupConfig)
+	if err != nil {
+		elog.ErrorCtx(context.Background(), "new kafka consumer group", elog.FieldName(key), elog.FieldError(err))
+		return nil, err
+	}
+
+	ConsumerGroupMap.Store(key, consumerGroup)
+	ecomponent.Register(ecomponent.KindKafkaConsumerGroup, key, closes.MQPriority, func() {
+		ConsumerGroupMap.Delete(key)
+		if err := consumerGroup.Close(); err != nil {
+			elog.ErrorCtx(context.Background(), "close kafka consumer group", elog.FieldName(key), elog.FieldError(err))
+		}
+	})
+	return consumerGroup, nil
+}
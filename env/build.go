@@ -0,0 +1,56 @@
+package env
+
+import (
+	"runtime"
+	"runtime/debug"
+)
+
+// 编译时通过 -ldflags "-X github.com/weblazy/easy/env.Version=v1.0.0" 注入
+var (
+	Version   string
+	GitCommit string
+	BuildTime string
+)
+
+// Build 编译信息
+type Build struct {
+	Version   string `json:"version"`
+	GitCommit string `json:"git_commit"`
+	BuildTime string `json:"build_time"`
+	GoVersion string `json:"go_version"`
+	Module    string `json:"module"`
+	Modified  bool   `json:"modified"` // 编译时工作区有未提交的修改
+}
+
+// BuildInfo 返回编译信息, 没有通过 ldflags 注入时使用 go 编译时记录的模块版本和 vcs 信息
+func BuildInfo() Build {
+	b := Build{
+		Version:   Version,
+		GitCommit: GitCommit,
+		BuildTime: BuildTime,
+		GoVersion: runtime.Version(),
+	}
+	info, ok := debug.ReadBuildInfo()
+	if !ok {
+		return b
+	}
+	b.Module = info.Main.Path
+	if b.Version == "" {
+		b.Version = info.Main.Version
+	}
+	for _, setting := range info.Settings {
+		switch setting.Key {
+		case "vcs.revision":
+			if b.GitCommit == "" {
+				b.GitCommit = setting.Value
+			}
+		case "vcs.time":
+			if b.BuildTime == "" {
+				b.BuildTime = setting.Value
+			}
+		case "vcs.modified":
+			b.Modified = setting.Value == "true"
+		}
+	}
+	return b
+}
@@ -6,7 +6,10 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 )
 
-func RunPrometheus(cfg *Config) {
-	http.Handle(cfg.Path, promhttp.Handler())
-	http.ListenAndServe(cfg.Port, nil)
+// RunPrometheus 使用单独的端口提供 metrics, 阻塞直到监听失败
+// 使用 http_server 时推荐开启 Admin, 随服务启动和关闭
+func RunPrometheus(cfg *Config) error {
+	mux := http.NewServeMux()
+	mux.Handle(cfg.Path, promhttp.Handler())
+	return http.ListenAndServe(cfg.Port, mux)
 }
@@ -0,0 +1,239 @@
+package etls
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/weblazy/easy/elog"
+	"google.golang.org/grpc/credentials"
+)
+
+const DefaultReloadInterval = time.Minute
+
+// ErrServerCertRequired server 端必须配置证书和私钥
+var ErrServerCertRequired = errors.New("etls: server credentials require CertFile and KeyFile")
+
+// Config TLS 证书配置
+type Config struct {
+	CertFile       string        // 证书文件
+	KeyFile        string        // 私钥文件
+	CaFile         string        // CA 证书文件, server 端配置后开启 mTLS 校验客户端证书, client 端用于校验服务端证书
+	ServerName     string        // client 端校验的服务端证书名称, 默认使用连接地址
+	ReloadInterval time.Duration // 证书文件变更检查间隔, 默认 1m, 小于 0 不检查
+}
+
+// Enable 是否配置了证书, client 端只配置 CaFile 时也会开启 TLS
+func (c Config) Enable() bool {
+	return c.CertFile != "" || c.CaFile != ""
+}
+
+// Reloader 加载证书文件, 并在文件变更后自动重新加载, 证书轮换不需要重启服务
+// 文件变更在握手时按 ReloadInterval 惰性检查, 重新加载失败时继续使用旧证书
+type Reloader struct {
+	config    Config
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	pool      *x509.CertPool
+	modTimes  map[string]time.Time
+	lastCheck time.Time
+}
+
+// NewReloader 加载证书文件
+func NewReloader(config Config) (*Reloader, error) {
+	if config.ReloadInterval == 0 {
+		config.ReloadInterval = DefaultReloadInterval
+	}
+	r := &Reloader{config: config}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *Reloader) files() []string {
+	files := make([]string, 0, 3)
+	for _, f := range []string{r.config.CertFile, r.config.KeyFile, r.config.CaFile} {
+		if f != "" {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+func (r *Reloader) load() error {
+	modTimes := make(map[string]time.Time)
+	for _, f := range r.files() {
+		info, err := os.Stat(f)
+		if err != nil {
+			return err
+		}
+		modTimes[f] = info.ModTime()
+	}
+
+	var cert *tls.Certificate
+	if r.config.CertFile != "" || r.config.KeyFile != "" {
+		c, err := tls.LoadX509KeyPair(r.config.CertFile, r.config.KeyFile)
+		if err != nil {
+			return err
+		}
+		cert = &c
+	}
+
+	var pool *x509.CertPool
+	if r.config.CaFile != "" {
+		ca, err := os.ReadFile(r.config.CaFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(ca) {
+			return fmt.Errorf("etls: no valid certificate in %s", r.config.CaFile)
+		}
+	}
+
+	r.mu.Lock()
+	r.cert = cert
+	r.pool = pool
+	r.modTimes = modTimes
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// changed 文件修改时间有变化时返回 true
+func (r *Reloader) changed() bool {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	for f, modTime := range r.modTimes {
+		info, err := os.Stat(f)
+		if err != nil {
+			// 轮换过程中文件可能短暂不存在, 下次再检查
+			return false
+		}
+		if !info.ModTime().Equal(modTime) {
+			return true
+		}
+	}
+	return false
+}
+
+// maybeReload 超过检查间隔时检查文件变更并重新加载
+func (r *Reloader) maybeReload() {
+	if r.config.ReloadInterval < 0 {
+		return
+	}
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < r.config.ReloadInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+
+	if !r.changed() {
+		return
+	}
+	if err := r.load(); err != nil {
+		elog.ErrorCtx(context.Background(), "etls reload certificate err", elog.FieldError(err))
+		return
+	}
+	elog.InfoCtx(context.Background(), "etls reload certificate success")
+}
+
+// Reload 立即重新加载证书文件
+func (r *Reloader) Reload() error {
+	return r.load()
+}
+
+// ServerConfig 当前证书对应的 server 端 tls 配置
+func (r *Reloader) ServerConfig() *tls.Config {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &tls.Config{MinVersion: tls.VersionTLS12}
+	if r.cert != nil {
+		c.Certificates = []tls.Certificate{*r.cert}
+	}
+	if r.pool != nil {
+		c.ClientCAs = r.pool
+		c.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return c
+}
+
+// ClientConfig 当前证书对应的 client 端 tls 配置
+func (r *Reloader) ClientConfig() *tls.Config {
+	r.maybeReload()
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	c := &tls.Config{MinVersion: tls.VersionTLS12, ServerName: r.config.ServerName}
+	if r.cert != nil {
+		c.Certificates = []tls.Certificate{*r.cert}
+	}
+	if r.pool != nil {
+		c.RootCAs = r.pool
+	}
+	return c
+}
+
+// credentials 每次握手使用 Reloader 的最新证书
+type reloadCredentials struct {
+	reloader   *Reloader
+	serverName string
+}
+
+// NewServerCredentials server 端 grpc 证书, 支持证书热加载
+func NewServerCredentials(config Config) (credentials.TransportCredentials, error) {
+	if config.CertFile == "" || config.KeyFile == "" {
+		return nil, ErrServerCertRequired
+	}
+	r, err := NewReloader(config)
+	if err != nil {
+		return nil, err
+	}
+	return &reloadCredentials{reloader: r}, nil
+}
+
+// NewClientCredentials client 端 grpc 证书, 支持证书热加载
+func NewClientCredentials(config Config) (credentials.TransportCredentials, error) {
+	r, err := NewReloader(config)
+	if err != nil {
+		return nil, err
+	}
+	return &reloadCredentials{reloader: r, serverName: config.ServerName}, nil
+}
+
+func (c *reloadCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	cfg := c.reloader.ClientConfig()
+	cfg.ServerName = c.serverName
+	return credentials.NewTLS(cfg).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *reloadCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return credentials.NewTLS(c.reloader.ServerConfig()).ServerHandshake(conn)
+}
+
+func (c *reloadCredentials) Info() credentials.ProtocolInfo {
+	return credentials.ProtocolInfo{
+		SecurityProtocol: "tls",
+		SecurityVersion:  "1.2",
+		ServerName:       c.serverName,
+	}
+}
+
+func (c *reloadCredentials) Clone() credentials.TransportCredentials {
+	return &reloadCredentials{reloader: c.reloader, serverName: c.serverName}
+}
+
+// Deprecated: use grpc.WithAuthority instead
+func (c *reloadCredentials) OverrideServerName(serverName string) error {
+	c.serverName = serverName
+	return nil
+}
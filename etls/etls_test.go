@@ -0,0 +1,147 @@
+package etls
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+func newTestCA(t *testing.T, dir string) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test-ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	assert.Nil(t, err)
+	cert, err := x509.ParseCertificate(der)
+	assert.Nil(t, err)
+	writePem(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", der)
+	return &testCA{cert: cert, key: key}
+}
+
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.Nil(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	assert.Nil(t, err)
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	assert.Nil(t, err)
+	writePem(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+	writePem(t, filepath.Join(dir, name+".key"), "EC PRIVATE KEY", keyDer)
+}
+
+func writePem(t *testing.T, file, typ string, der []byte) {
+	err := os.WriteFile(file, pem.EncodeToMemory(&pem.Block{Type: typ, Bytes: der}), 0600)
+	assert.Nil(t, err)
+}
+
+func TestMutualTLS(t *testing.T) {
+	serverDir, clientDir := t.TempDir(), t.TempDir()
+	ca := newTestCA(t, serverDir)
+	ca.issue(t, serverDir, "server", 2)
+	ca.issue(t, clientDir, "client", 3)
+	clientCert, clientKey := filepath.Join(clientDir, "client.pem"), filepath.Join(clientDir, "client.key")
+
+	serverCreds, err := NewServerCredentials(Config{
+		CertFile: filepath.Join(serverDir, "server.pem"),
+		KeyFile:  filepath.Join(serverDir, "server.key"),
+		CaFile:   filepath.Join(serverDir, "ca.pem"),
+	})
+	assert.Nil(t, err)
+	clientCreds, err := NewClientCredentials(Config{
+		CertFile:       clientCert,
+		KeyFile:        clientKey,
+		CaFile:         filepath.Join(serverDir, "ca.pem"),
+		ServerName:     "server",
+		ReloadInterval: time.Nanosecond,
+	})
+	assert.Nil(t, err)
+
+	peerCN := make(chan string, 1)
+	server := grpc.NewServer(grpc.Creds(serverCreds), grpc.UnaryInterceptor(
+		func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			peerCN <- GetPeerCommonName(ctx)
+			return handler(ctx, req)
+		}))
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+	defer server.Stop()
+
+	check := func(creds grpc.DialOption) error {
+		cc, err := grpc.Dial("bufnet", creds, grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return listener.Dial()
+		}))
+		assert.Nil(t, err)
+		defer cc.Close()
+		_, err = healthpb.NewHealthClient(cc).Check(context.Background(), &healthpb.HealthCheckRequest{})
+		return err
+	}
+
+	assert.Nil(t, check(grpc.WithTransportCredentials(clientCreds)))
+	assert.Equal(t, "client", <-peerCN)
+
+	// 证书轮换后新连接使用新证书
+	ca.issue(t, clientDir, "client", 4)
+	later := time.Now().Add(time.Second)
+	assert.Nil(t, os.Chtimes(clientCert, later, later))
+	assert.Nil(t, check(grpc.WithTransportCredentials(clientCreds)))
+	assert.Equal(t, "client", <-peerCN)
+	identity := clientCreds.(*reloadCredentials).reloader.ClientConfig().Certificates[0]
+	leaf, err := x509.ParseCertificate(identity.Certificate[0])
+	assert.Nil(t, err)
+	assert.Equal(t, int64(4), leaf.SerialNumber.Int64())
+
+	// 没有客户端证书时 mTLS 握手失败
+	noCertCreds, err := NewClientCredentials(Config{CaFile: filepath.Join(serverDir, "ca.pem"), ServerName: "server"})
+	assert.Nil(t, err)
+	assert.NotNil(t, check(grpc.WithTransportCredentials(noCertCreds)))
+}
+
+func TestNewReloader_invalid(t *testing.T) {
+	_, err := NewReloader(Config{CertFile: "not_exist.pem", KeyFile: "not_exist.key"})
+	assert.NotNil(t, err)
+	assert.False(t, Config{}.Enable())
+	assert.True(t, Config{CaFile: "ca.pem"}.Enable())
+
+	// server 端只配置 CA 时没有证书可用
+	_, err = NewServerCredentials(Config{CaFile: "ca.pem"})
+	assert.Equal(t, ErrServerCertRequired, err)
+}
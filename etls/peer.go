@@ -0,0 +1,57 @@
+package etls
+
+import (
+	"context"
+	"crypto/x509"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/peer"
+)
+
+// PeerIdentity 对端证书身份信息
+type PeerIdentity struct {
+	CommonName   string
+	DNSNames     []string
+	URIs         []string // 如 spiffe://cluster.local/ns/default/sa/user
+	SerialNumber string
+}
+
+// NewPeerIdentity 从证书中提取身份信息
+func NewPeerIdentity(cert *x509.Certificate) *PeerIdentity {
+	uris := make([]string, 0, len(cert.URIs))
+	for _, u := range cert.URIs {
+		uris = append(uris, u.String())
+	}
+	return &PeerIdentity{
+		CommonName:   cert.Subject.CommonName,
+		DNSNames:     cert.DNSNames,
+		URIs:         uris,
+		SerialNumber: cert.SerialNumber.String(),
+	}
+}
+
+// GetPeerIdentity 获取 grpc 对端证书身份, 非 TLS 连接或对端没有证书时返回 false
+func GetPeerIdentity(ctx context.Context) (*PeerIdentity, bool) {
+	pr, ok := peer.FromContext(ctx)
+	if !ok {
+		return nil, false
+	}
+	tlsInfo, ok := pr.AuthInfo.(credentials.TLSInfo)
+	if !ok {
+		return nil, false
+	}
+	certs := tlsInfo.State.PeerCertificates
+	if len(certs) == 0 {
+		return nil, false
+	}
+	return NewPeerIdentity(certs[0]), true
+}
+
+// GetPeerCommonName 获取 grpc 对端证书 CommonName
+func GetPeerCommonName(ctx context.Context) string {
+	identity, ok := GetPeerIdentity(ctx)
+	if !ok {
+		return ""
+	}
+	return identity.CommonName
+}
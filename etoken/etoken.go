@@ -0,0 +1,303 @@
+package etoken
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/weblazy/easy/elog"
+)
+
+// 签名算法
+const (
+	HS256 = "HS256"
+	RS256 = "RS256"
+)
+
+// token 类型, 刷新 token 不能用于接口鉴权
+const (
+	TypeAccess  = "access"
+	TypeRefresh = "refresh"
+)
+
+var (
+	ErrTokenInvalid = errors.New("token is invalid")
+	ErrTokenRevoked = errors.New("token is revoked")
+	ErrTokenType    = errors.New("token type mismatch")
+	ErrKeyNotFound  = errors.New("token signing key not found")
+	ErrRevokerNil   = errors.New("token revoker is nil")
+)
+
+// KeyConfig 签名密钥, HS256 使用 Secret, RS256 使用 PEM 格式的 PrivateKey 和 PublicKey
+// 轮换密钥时保留旧密钥用于校验, 只有 PublicKey 或 Secret 的密钥不会用于签发
+type KeyConfig struct {
+	Kid        string // 密钥 ID, 写入 jwt header 的 kid
+	Secret     string // HS256 密钥
+	PrivateKey string // RS256 私钥, PEM 格式
+	PublicKey  string // RS256 公钥, PEM 格式
+}
+
+// Config token 配置
+type Config struct {
+	Issuer        string        // 签发方, 校验时必须一致
+	Algorithm     string        // 签名算法, HS256 或 RS256, 默认 HS256
+	Keys          []KeyConfig   // 所有可用的密钥, 用于校验
+	ActiveKid     string        // 签发使用的密钥, 默认使用第一个
+	AccessExpiry  time.Duration // access token 有效期, 默认 2h
+	RefreshExpiry time.Duration // refresh token 有效期, 默认 7 天
+	Leeway        time.Duration // 校验过期时间时允许的时钟偏差, 默认 0
+}
+
+// DefaultConfig 默认配置
+func DefaultConfig() *Config {
+	return &Config{
+		Algorithm:     HS256,
+		AccessExpiry:  2 * time.Hour,
+		RefreshExpiry: 7 * 24 * time.Hour,
+	}
+}
+
+// Claims jwt 内容, Extra 为业务自定义字段
+type Claims struct {
+	Uid   string                 `json:"uid"`
+	Type  string                 `json:"typ"`
+	Extra map[string]interface{} `json:"ext,omitempty"`
+	jwt.RegisteredClaims
+}
+
+// TokenPair 签发的 token
+type TokenPair struct {
+	AccessToken      string    `json:"access_token"`
+	RefreshToken     string    `json:"refresh_token"`
+	AccessExpiresAt  time.Time `json:"access_expires_at"`
+	RefreshExpiresAt time.Time `json:"refresh_expires_at"`
+}
+
+type key struct {
+	sign   interface{}
+	verify interface{}
+}
+
+// Manager 签发, 校验, 刷新和吊销 token
+type Manager struct {
+	config  *Config
+	method  jwt.SigningMethod
+	keys    map[string]key
+	revoker Revoker
+}
+
+// NewManager 创建 token 管理, revoker 为空时不支持吊销和刷新
+func NewManager(config *Config, revoker Revoker) (*Manager, error) {
+	c := *DefaultConfig()
+	if config != nil {
+		c = *config
+		if c.Algorithm == "" {
+			c.Algorithm = HS256
+		}
+		if c.AccessExpiry <= 0 {
+			c.AccessExpiry = DefaultConfig().AccessExpiry
+		}
+		if c.RefreshExpiry <= 0 {
+			c.RefreshExpiry = DefaultConfig().RefreshExpiry
+		}
+	}
+	if len(c.Keys) == 0 {
+		return nil, ErrKeyNotFound
+	}
+	if c.ActiveKid == "" {
+		c.ActiveKid = c.Keys[0].Kid
+	}
+
+	m := &Manager{config: &c, keys: make(map[string]key, len(c.Keys)), revoker: revoker}
+	switch c.Algorithm {
+	case HS256:
+		m.method = jwt.SigningMethodHS256
+	case RS256:
+		m.method = jwt.SigningMethodRS256
+	default:
+		return nil, fmt.Errorf("unsupported token algorithm %s", c.Algorithm)
+	}
+	for _, kc := range c.Keys {
+		k, err := parseKey(c.Algorithm, kc)
+		if err != nil {
+			return nil, fmt.Errorf("parse token key %s: %w", kc.Kid, err)
+		}
+		m.keys[kc.Kid] = k
+	}
+	if m.keys[c.ActiveKid].sign == nil {
+		return nil, fmt.Errorf("%w: active kid %s", ErrKeyNotFound, c.ActiveKid)
+	}
+	return m, nil
+}
+
+func parseKey(algorithm string, kc KeyConfig) (key, error) {
+	if algorithm == HS256 {
+		if kc.Secret == "" {
+			return key{}, errors.New("secret is empty")
+		}
+		return key{sign: []byte(kc.Secret), verify: []byte(kc.Secret)}, nil
+	}
+	var k key
+	if kc.PrivateKey != "" {
+		privateKey, err := jwt.ParseRSAPrivateKeyFromPEM([]byte(kc.PrivateKey))
+		if err != nil {
+			return key{}, err
+		}
+		k.sign, k.verify = privateKey, &privateKey.PublicKey
+	}
+	if kc.PublicKey != "" {
+		publicKey, err := jwt.ParseRSAPublicKeyFromPEM([]byte(kc.PublicKey))
+		if err != nil {
+			return key{}, err
+		}
+		k.verify = publicKey
+	}
+	if k.verify == nil {
+		return key{}, errors.New("public key is empty")
+	}
+	return k, nil
+}
+
+// Issue 签发 access token 和 refresh token, extra 为自定义字段, 刷新时保留
+func (m *Manager) Issue(uid string, extra map[string]interface{}) (*TokenPair, error) {
+	now := time.Now()
+	pair := &TokenPair{
+		AccessExpiresAt:  now.Add(m.config.AccessExpiry),
+		RefreshExpiresAt: now.Add(m.config.RefreshExpiry),
+	}
+	var err error
+	if pair.AccessToken, err = m.sign(uid, TypeAccess, extra, now, pair.AccessExpiresAt); err != nil {
+		return nil, err
+	}
+	if pair.RefreshToken, err = m.sign(uid, TypeRefresh, extra, now, pair.RefreshExpiresAt); err != nil {
+		return nil, err
+	}
+	return pair, nil
+}
+
+func (m *Manager) sign(uid, typ string, extra map[string]interface{}, now, expiresAt time.Time) (string, error) {
+	claims := &Claims{
+		Uid:   uid,
+		Type:  typ,
+		Extra: extra,
+		RegisteredClaims: jwt.RegisteredClaims{
+			ID:        newJti(),
+			Issuer:    m.config.Issuer,
+			Subject:   uid,
+			IssuedAt:  jwt.NewNumericDate(now),
+			NotBefore: jwt.NewNumericDate(now),
+			ExpiresAt: jwt.NewNumericDate(expiresAt),
+		},
+	}
+	token := jwt.NewWithClaims(m.method, claims)
+	token.Header["kid"] = m.config.ActiveKid
+	return token.SignedString(m.keys[m.config.ActiveKid].sign)
+}
+
+func newJti() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Parse 校验 access token, 返回 Claims
+func (m *Manager) Parse(ctx context.Context, token string) (*Claims, error) {
+	return m.parse(ctx, token, TypeAccess)
+}
+
+func (m *Manager) parse(ctx context.Context, tokenString, typ string) (*Claims, error) {
+	claims := &Claims{}
+	parser := jwt.NewParser(jwt.WithValidMethods([]string{m.method.Alg()}))
+	token, err := parser.ParseWithClaims(tokenString, claims, func(token *jwt.Token) (interface{}, error) {
+		kid, _ := token.Header["kid"].(string)
+		k, ok := m.keys[kid]
+		if !ok {
+			return nil, fmt.Errorf("%w: kid %s", ErrKeyNotFound, kid)
+		}
+		return k.verify, nil
+	})
+	if err != nil && !m.withinLeeway(err, claims) {
+		return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+	if token == nil || (err == nil && !token.Valid) {
+		return nil, ErrTokenInvalid
+	}
+	if m.config.Issuer != "" && !claims.VerifyIssuer(m.config.Issuer, true) {
+		return nil, fmt.Errorf("%w: issuer %s", ErrTokenInvalid, claims.Issuer)
+	}
+	if claims.Type != typ {
+		return nil, ErrTokenType
+	}
+	if m.revoker != nil {
+		revoked, err := m.revoker.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			// 吊销列表不可用时不影响正常请求
+			elog.ErrorCtx(ctx, "check token revoked", elog.FieldError(err))
+		} else if revoked {
+			return nil, ErrTokenRevoked
+		}
+	}
+	return claims, nil
+}
+
+// withinLeeway 只有过期时间在允许的时钟偏差内时忽略过期错误
+func (m *Manager) withinLeeway(err error, claims *Claims) bool {
+	var ve *jwt.ValidationError
+	if m.config.Leeway <= 0 || !errors.As(err, &ve) || ve.Errors != jwt.ValidationErrorExpired {
+		return false
+	}
+	return claims.ExpiresAt != nil && time.Since(claims.ExpiresAt.Time) <= m.config.Leeway
+}
+
+// Refresh 使用 refresh token 签发新的 token, 旧的 refresh token 被吊销, 不能重复使用
+// 并发刷新同一个 refresh token 时只有一个成功, 没有吊销列表时无法保证只使用一次, 返回 ErrRevokerNil
+func (m *Manager) Refresh(ctx context.Context, refreshToken string) (*TokenPair, error) {
+	if m.revoker == nil {
+		return nil, ErrRevokerNil
+	}
+	claims, err := m.parse(ctx, refreshToken, TypeRefresh)
+	if err != nil {
+		return nil, err
+	}
+	revoked, err := m.revoker.Revoke(ctx, claims.ID, time.Until(claims.ExpiresAt.Time)+m.config.Leeway)
+	if err != nil {
+		return nil, err
+	}
+	if !revoked {
+		return nil, ErrTokenRevoked
+	}
+	return m.Issue(claims.Uid, claims.Extra)
+}
+
+// Revoke 吊销 token, 吊销记录保存到 token 过期
+func (m *Manager) Revoke(ctx context.Context, token string) error {
+	if m.revoker == nil {
+		return ErrRevokerNil
+	}
+	claims := &Claims{}
+	// 已经过期或签名错误的 token 不需要吊销
+	if _, _, err := jwt.NewParser().ParseUnverified(token, claims); err != nil {
+		return fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+	}
+	if _, err := m.parse(ctx, token, claims.Type); err != nil {
+		if errors.Is(err, ErrTokenRevoked) {
+			return nil
+		}
+		return err
+	}
+	_, err := m.revoker.Revoke(ctx, claims.ID, time.Until(claims.ExpiresAt.Time)+m.config.Leeway)
+	return err
+}
+
+// ValidateToken 校验 access token 返回 uid, 用于 http interceptor.Token 和 grpc interceptor.NewTokenAuth
+func (m *Manager) ValidateToken(token string) (string, error) {
+	claims, err := m.Parse(context.Background(), token)
+	if err != nil {
+		return "", err
+	}
+	return claims.Uid, nil
+}
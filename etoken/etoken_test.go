@@ -0,0 +1,206 @@
+package etoken
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/econfig/eviper"
+	"github.com/weblazy/easy/http/http_server/interceptor"
+)
+
+func newHS256Manager(t *testing.T, revoker Revoker) *Manager {
+	m, err := NewManager(&Config{
+		Issuer: "easy",
+		Keys:   []KeyConfig{{Kid: "k1", Secret: "secret1"}},
+	}, revoker)
+	assert.Nil(t, err)
+	return m
+}
+
+func TestManager_HS256(t *testing.T) {
+	m := newHS256Manager(t, nil)
+	pair, err := m.Issue("1001", map[string]interface{}{"role": "admin"})
+	assert.Nil(t, err)
+
+	claims, err := m.Parse(context.Background(), pair.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "1001", claims.Uid)
+	assert.Equal(t, "admin", claims.Extra["role"])
+	assert.Equal(t, "easy", claims.Issuer)
+
+	uid, err := m.ValidateToken(pair.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "1001", uid)
+
+	// refresh token 不能用于鉴权
+	_, err = m.ValidateToken(pair.RefreshToken)
+	assert.True(t, errors.Is(err, ErrTokenType))
+
+	// 签发方不一致
+	other, err := NewManager(&Config{Issuer: "other", Keys: []KeyConfig{{Kid: "k1", Secret: "secret1"}}}, nil)
+	assert.Nil(t, err)
+	_, err = other.ValidateToken(pair.AccessToken)
+	assert.True(t, errors.Is(err, ErrTokenInvalid))
+
+	// 篡改
+	_, err = m.ValidateToken(pair.AccessToken + "x")
+	assert.True(t, errors.Is(err, ErrTokenInvalid))
+}
+
+func TestManager_Expired(t *testing.T) {
+	m, err := NewManager(&Config{Keys: []KeyConfig{{Kid: "k1", Secret: "secret1"}}}, nil)
+	assert.Nil(t, err)
+	now := time.Now()
+	token, err := m.sign("1001", TypeAccess, nil, now.Add(-time.Hour), now.Add(-time.Second))
+	assert.Nil(t, err)
+	_, err = m.ValidateToken(token)
+	assert.True(t, errors.Is(err, ErrTokenInvalid))
+
+	m.config.Leeway = time.Minute
+	uid, err := m.ValidateToken(token)
+	assert.Nil(t, err)
+	assert.Equal(t, "1001", uid)
+}
+
+func generateRSA(t *testing.T) (string, string) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.Nil(t, err)
+	privateKey := pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+	publicBytes, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	assert.Nil(t, err)
+	publicKey := pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: publicBytes})
+	return string(privateKey), string(publicKey)
+}
+
+func TestManager_RS256Rotation(t *testing.T) {
+	private1, public1 := generateRSA(t)
+	private2, _ := generateRSA(t)
+
+	old, err := NewManager(&Config{Algorithm: RS256, Keys: []KeyConfig{{Kid: "k1", PrivateKey: private1}}}, nil)
+	assert.Nil(t, err)
+	oldPair, err := old.Issue("1001", nil)
+	assert.Nil(t, err)
+
+	// 轮换后使用 k2 签发, k1 只保留公钥用于校验
+	m, err := NewManager(&Config{
+		Algorithm: RS256,
+		ActiveKid: "k2",
+		Keys:      []KeyConfig{{Kid: "k1", PublicKey: public1}, {Kid: "k2", PrivateKey: private2}},
+	}, nil)
+	assert.Nil(t, err)
+	pair, err := m.Issue("1002", nil)
+	assert.Nil(t, err)
+
+	token, _, err := jwt.NewParser().ParseUnverified(pair.AccessToken, &Claims{})
+	assert.Nil(t, err)
+	assert.Equal(t, "k2", token.Header["kid"])
+
+	uid, err := m.ValidateToken(oldPair.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "1001", uid)
+	uid, err = m.ValidateToken(pair.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "1002", uid)
+
+	// 旧的服务不认识 k2
+	_, err = old.ValidateToken(pair.AccessToken)
+	assert.True(t, errors.Is(err, ErrTokenInvalid))
+
+	// 只有公钥的密钥不能签发
+	_, err = NewManager(&Config{Algorithm: RS256, Keys: []KeyConfig{{Kid: "k1", PublicKey: public1}}}, nil)
+	assert.True(t, errors.Is(err, ErrKeyNotFound))
+
+	// 算法不一致
+	_, err = newHS256Manager(t, nil).ValidateToken(pair.AccessToken)
+	assert.True(t, errors.Is(err, ErrTokenInvalid))
+}
+
+func TestManager_RefreshAndRevoke(t *testing.T) {
+	ctx := context.Background()
+	m := newHS256Manager(t, NewMemoryRevoker())
+	pair, err := m.Issue("1001", map[string]interface{}{"role": "admin"})
+	assert.Nil(t, err)
+
+	newPair, err := m.Refresh(ctx, pair.RefreshToken)
+	assert.Nil(t, err)
+	claims, err := m.Parse(ctx, newPair.AccessToken)
+	assert.Nil(t, err)
+	assert.Equal(t, "1001", claims.Uid)
+	assert.Equal(t, "admin", claims.Extra["role"])
+
+	// refresh token 只能使用一次
+	_, err = m.Refresh(ctx, pair.RefreshToken)
+	assert.True(t, errors.Is(err, ErrTokenRevoked))
+
+	// access token 不能用于刷新
+	_, err = m.Refresh(ctx, newPair.AccessToken)
+	assert.True(t, errors.Is(err, ErrTokenType))
+
+	assert.Nil(t, m.Revoke(ctx, newPair.AccessToken))
+	_, err = m.ValidateToken(newPair.AccessToken)
+	assert.True(t, errors.Is(err, ErrTokenRevoked))
+	// 重复吊销
+	assert.Nil(t, m.Revoke(ctx, newPair.AccessToken))
+
+	assert.Equal(t, ErrRevokerNil, newHS256Manager(t, nil).Revoke(ctx, newPair.AccessToken))
+	_, err = newHS256Manager(t, nil).Refresh(ctx, newPair.RefreshToken)
+	assert.Equal(t, ErrRevokerNil, err)
+}
+
+func TestManager_RefreshConcurrent(t *testing.T) {
+	ctx := context.Background()
+	m := newHS256Manager(t, NewMemoryRevoker())
+	pair, err := m.Issue("1001", nil)
+	assert.Nil(t, err)
+
+	// 并发刷新同一个 refresh token 只有一个成功
+	var success int32
+	var wg sync.WaitGroup
+	for i := 0; i < 10; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := m.Refresh(ctx, pair.RefreshToken); err == nil {
+				atomic.AddInt32(&success, 1)
+			} else {
+				assert.True(t, errors.Is(err, ErrTokenRevoked))
+			}
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(1), success)
+}
+
+func TestManager_HttpToken(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	econfig.GlobalViper = eviper.NewViperFromString("")
+	m := newHS256Manager(t, nil)
+	pair, err := m.Issue("1001", nil)
+	assert.Nil(t, err)
+
+	r := gin.New()
+	r.Use(interceptor.Token("X-User-Id", m.ValidateToken))
+	r.GET("/user", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Request.Header.Get("X-User-Id"))
+	})
+
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/user", nil)
+	req.Header.Set(interceptor.TokenHeader, pair.AccessToken)
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "1001", w.Body.String())
+}
@@ -0,0 +1,86 @@
+package etoken
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/weblazy/easy/db/eredis"
+)
+
+// DefaultRevokePrefix redis 中吊销 token 的 key 前缀
+const DefaultRevokePrefix = "token_revoked#"
+
+// Revoker 记录被吊销的 token, 使用 jti 作为标识
+type Revoker interface {
+	// Revoke 吊销 token, expire 后记录可以删除, 已经被吊销时返回 false, 用于保证 refresh token 只能使用一次
+	Revoke(ctx context.Context, jti string, expire time.Duration) (bool, error)
+	// IsRevoked 判断 token 是否被吊销
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+}
+
+// MemoryRevoker 内存吊销列表, 适用于单实例部署和单测
+type MemoryRevoker struct {
+	mu      sync.Mutex
+	revoked map[string]time.Time
+}
+
+// NewMemoryRevoker 创建内存吊销列表
+func NewMemoryRevoker() *MemoryRevoker {
+	return &MemoryRevoker{revoked: make(map[string]time.Time)}
+}
+
+func (r *MemoryRevoker) Revoke(ctx context.Context, jti string, expire time.Duration) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	now := time.Now()
+	// 写入时清理已过期的记录
+	for k, expiresAt := range r.revoked {
+		if now.After(expiresAt) {
+			delete(r.revoked, k)
+		}
+	}
+	if _, ok := r.revoked[jti]; ok {
+		return false, nil
+	}
+	r.revoked[jti] = now.Add(expire)
+	return true, nil
+}
+
+func (r *MemoryRevoker) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	expiresAt, ok := r.revoked[jti]
+	return ok && time.Now().Before(expiresAt), nil
+}
+
+// RedisRevoker 基于 redis 的吊销列表, 适用于多实例部署
+type RedisRevoker struct {
+	client *eredis.RedisClient
+	prefix string
+}
+
+// NewRedisRevoker 创建 redis 吊销列表, prefix 为空时使用 DefaultRevokePrefix
+func NewRedisRevoker(client *eredis.RedisClient, prefix string) *RedisRevoker {
+	if prefix == "" {
+		prefix = DefaultRevokePrefix
+	}
+	return &RedisRevoker{client: client, prefix: prefix}
+}
+
+// Revoke 使用 SETNX, 并发吊销同一个 token 时只有一个返回 true
+func (r *RedisRevoker) Revoke(ctx context.Context, jti string, expire time.Duration) (bool, error) {
+	// token 已经过期, 不需要记录
+	if expire <= 0 {
+		return true, nil
+	}
+	return r.client.SetNX(ctx, r.prefix+jti, 1, expire).Result()
+}
+
+func (r *RedisRevoker) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	n, err := r.client.Exists(ctx, r.prefix+jti).Result()
+	if err != nil {
+		return false, err
+	}
+	return n > 0, nil
+}
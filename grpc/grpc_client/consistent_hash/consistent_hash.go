@@ -0,0 +1,127 @@
+package consistent_hash
+
+import (
+	"context"
+	"sort"
+	"strconv"
+	"sync/atomic"
+
+	"github.com/cespare/xxhash/v2"
+	"github.com/weblazy/easy/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/metadata"
+)
+
+// Name grpc_client BalancerName 配置为 consistent_hash 时按照 hash key 一致性哈希选择连接
+const Name = "consistent_hash"
+
+const (
+	// DefaultHashKey 默认使用透传的用户 ID 作为 hash key
+	DefaultHashKey = transport.PrefixPass + "uid"
+	// DefaultReplicas 每个地址的虚拟节点数
+	DefaultReplicas = 160
+)
+
+func init() {
+	balancer.Register(NewBuilder(DefaultReplicas))
+}
+
+type hashKeyCtx struct{}
+
+// WithHashKey 设置请求的 hash key, 相同 hash key 的请求会发送到同一个连接
+func WithHashKey(ctx context.Context, key string) context.Context {
+	return context.WithValue(ctx, hashKeyCtx{}, key)
+}
+
+// HashKeyFromContext 获取请求的 hash key
+func HashKeyFromContext(ctx context.Context) (string, bool) {
+	key, ok := ctx.Value(hashKeyCtx{}).(string)
+	return key, ok && key != ""
+}
+
+// HashKeyUnaryClientInterceptor 从 ctx 中读取 hash key, 优先使用 WithHashKey 设置的值, 其次是 outgoing metadata 和透传参数中的 key
+func HashKeyUnaryClientInterceptor(key string) grpc.UnaryClientInterceptor {
+	if key == "" {
+		key = DefaultHashKey
+	}
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if _, ok := HashKeyFromContext(ctx); !ok {
+			if value := hashKeyFromMetadata(ctx, key); value != "" {
+				ctx = WithHashKey(ctx, value)
+			}
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
+
+func hashKeyFromMetadata(ctx context.Context, key string) string {
+	if md, ok := metadata.FromOutgoingContext(ctx); ok {
+		if values := md.Get(key); len(values) > 0 {
+			return values[0]
+		}
+	}
+	return transport.GetMapFromContext(ctx)[key]
+}
+
+// NewBuilder 创建一致性哈希负载均衡, replicas 为每个地址的虚拟节点数
+func NewBuilder(replicas int) balancer.Builder {
+	if replicas <= 0 {
+		replicas = DefaultReplicas
+	}
+	return base.NewBalancerBuilder(Name, &pickerBuilder{replicas: replicas}, base.Config{HealthCheck: true})
+}
+
+type pickerBuilder struct {
+	replicas int
+}
+
+type ringNode struct {
+	hash    uint64
+	subConn balancer.SubConn
+}
+
+func (b *pickerBuilder) Build(info base.PickerBuildInfo) balancer.Picker {
+	if len(info.ReadySCs) == 0 {
+		return base.NewErrPicker(balancer.ErrNoSubConnAvailable)
+	}
+	p := &picker{
+		ring:     make([]ringNode, 0, len(info.ReadySCs)*b.replicas),
+		subConns: make([]balancer.SubConn, 0, len(info.ReadySCs)),
+	}
+	// 虚拟节点只和地址相关, 地址变更时只有相邻区间的 key 重新映射
+	for sc, scInfo := range info.ReadySCs {
+		p.subConns = append(p.subConns, sc)
+		for i := 0; i < b.replicas; i++ {
+			p.ring = append(p.ring, ringNode{hash: xxhash.Sum64String(scInfo.Address.Addr + "#" + strconv.Itoa(i)), subConn: sc})
+		}
+	}
+	sort.Slice(p.ring, func(i, j int) bool {
+		return p.ring[i].hash < p.ring[j].hash
+	})
+	return p
+}
+
+type picker struct {
+	ring     []ringNode
+	subConns []balancer.SubConn
+	next     uint32
+}
+
+// Pick 有 hash key 时使用哈希环上顺时针第一个节点, 没有时使用 round robin
+func (p *picker) Pick(info balancer.PickInfo) (balancer.PickResult, error) {
+	key, ok := HashKeyFromContext(info.Ctx)
+	if !ok {
+		next := atomic.AddUint32(&p.next, 1)
+		return balancer.PickResult{SubConn: p.subConns[next%uint32(len(p.subConns))]}, nil
+	}
+	hash := xxhash.Sum64String(key)
+	i := sort.Search(len(p.ring), func(i int) bool {
+		return p.ring[i].hash >= hash
+	})
+	if i == len(p.ring) {
+		i = 0
+	}
+	return balancer.PickResult{SubConn: p.ring[i].subConn}, nil
+}
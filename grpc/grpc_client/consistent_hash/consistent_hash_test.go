@@ -0,0 +1,106 @@
+package consistent_hash
+
+import (
+	"context"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/balancer"
+	"google.golang.org/grpc/balancer/base"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/resolver"
+)
+
+type mockSubConn struct {
+	addr string
+}
+
+func (m *mockSubConn) UpdateAddresses([]resolver.Address) {}
+
+func (m *mockSubConn) Connect() {}
+
+func (m *mockSubConn) GetOrBuildProducer(balancer.ProducerBuilder) (balancer.Producer, func()) {
+	return nil, func() {}
+}
+
+func buildPicker(subConns ...*mockSubConn) balancer.Picker {
+	info := base.PickerBuildInfo{ReadySCs: make(map[balancer.SubConn]base.SubConnInfo)}
+	for _, sc := range subConns {
+		info.ReadySCs[sc] = base.SubConnInfo{Address: resolver.Address{Addr: sc.addr}}
+	}
+	return (&pickerBuilder{replicas: DefaultReplicas}).Build(info)
+}
+
+func pick(t *testing.T, p balancer.Picker, ctx context.Context) string {
+	res, err := p.Pick(balancer.PickInfo{Ctx: ctx})
+	assert.Nil(t, err)
+	return res.SubConn.(*mockSubConn).addr
+}
+
+func TestPicker(t *testing.T) {
+	a, b, c := &mockSubConn{addr: "10.0.0.1:9090"}, &mockSubConn{addr: "10.0.0.2:9090"}, &mockSubConn{addr: "10.0.0.3:9090"}
+	p := buildPicker(a, b, c)
+
+	// 相同 key 选择相同连接, 并且分布到所有连接
+	before := make(map[string]string)
+	counts := make(map[string]int)
+	for i := 0; i < 3000; i++ {
+		key := strconv.Itoa(i)
+		addr := pick(t, p, WithHashKey(context.Background(), key))
+		assert.Equal(t, addr, pick(t, p, WithHashKey(context.Background(), key)))
+		before[key] = addr
+		counts[addr]++
+	}
+	assert.Len(t, counts, 3)
+	for _, count := range counts {
+		assert.Greater(t, count, 500)
+	}
+
+	// 删除一个地址时只有该地址的 key 重新映射
+	p = buildPicker(a, b)
+	for key, addr := range before {
+		if addr != c.addr {
+			assert.Equal(t, addr, pick(t, p, WithHashKey(context.Background(), key)))
+		}
+	}
+
+	// 没有 key 时 round robin
+	counts = make(map[string]int)
+	for i := 0; i < 10; i++ {
+		counts[pick(t, p, context.Background())]++
+	}
+	assert.Equal(t, map[string]int{a.addr: 5, b.addr: 5}, counts)
+
+	// 没有可用连接
+	_, err := buildPicker().Pick(balancer.PickInfo{Ctx: context.Background()})
+	assert.Equal(t, balancer.ErrNoSubConnAvailable, err)
+}
+
+func TestHashKeyUnaryClientInterceptor(t *testing.T) {
+	var got string
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		got, _ = HashKeyFromContext(ctx)
+		return nil
+	}
+
+	mw := HashKeyUnaryClientInterceptor("")
+	assert.Nil(t, mw(transport.WithValue(context.Background(), DefaultHashKey, "1001"), "/user.UserService/GetUserInfo", nil, nil, nil, invoker))
+	assert.Equal(t, "1001", got)
+
+	got = ""
+	assert.Nil(t, mw(context.Background(), "/user.UserService/GetUserInfo", nil, nil, nil, invoker))
+	assert.Equal(t, "", got)
+
+	// WithHashKey 优先
+	ctx := WithHashKey(transport.WithValue(context.Background(), DefaultHashKey, "1001"), "1002")
+	assert.Nil(t, mw(ctx, "/user.UserService/GetUserInfo", nil, nil, nil, invoker))
+	assert.Equal(t, "1002", got)
+
+	mw = HashKeyUnaryClientInterceptor("x-shard")
+	ctx = metadata.AppendToOutgoingContext(context.Background(), "x-shard", "s1")
+	assert.Nil(t, mw(ctx, "/user.UserService/GetUserInfo", nil, nil, nil, invoker))
+	assert.Equal(t, "s1", got)
+}
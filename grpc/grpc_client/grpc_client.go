@@ -6,7 +6,9 @@ import (
 	"time"
 
 	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/etls"
 	"github.com/weblazy/easy/grpc/grpc_client/grpc_client_config"
+	_ "github.com/weblazy/easy/grpc/grpc_client/nacos_resolver"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/credentials/insecure"
 
@@ -35,8 +37,8 @@ func NewGrpcClient(config *grpc_client_config.Config) *GrpcClient {
 	config.BuildDialOptions()
 
 	var dialOptions = config.DialOptions
-	// 默认配置使用block
-	if config.EnableBlock {
+	// 默认配置使用block, 回放模式不连接下游
+	if config.EnableBlock && !config.IsReplay() {
 		if config.DialTimeout > time.Duration(0) {
 			var cancel context.CancelFunc
 			ctx, cancel = context.WithTimeout(ctx, config.DialTimeout)
@@ -46,7 +48,15 @@ func NewGrpcClient(config *grpc_client_config.Config) *GrpcClient {
 		dialOptions = append(dialOptions, grpc.WithBlock())
 	}
 
-	if config.EnableWithInsecure {
+	// 配置了证书优先使用 TLS
+	if tlsConfig := config.TLSConfig(); tlsConfig.Enable() {
+		creds, err := etls.NewClientCredentials(tlsConfig)
+		if err != nil {
+			elog.ErrorCtx(emptyCtx, "load grpc client certificate", elog.FieldError(err), elog.FieldName(config.Name))
+			return &GrpcClient{config: config, err: err}
+		}
+		dialOptions = append(dialOptions, grpc.WithTransportCredentials(creds))
+	} else if config.EnableWithInsecure {
 		dialOptions = append(dialOptions, grpc.WithTransportCredentials(insecure.NewCredentials()))
 	}
 
@@ -71,6 +81,7 @@ func NewGrpcClient(config *grpc_client_config.Config) *GrpcClient {
 	client := &GrpcClient{
 		config:     config,
 		ClientConn: cc,
+		err:        err,
 	}
 
 	if err != nil {
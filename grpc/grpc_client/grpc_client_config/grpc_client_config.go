@@ -1,12 +1,20 @@
 package grpc_client_config
 
 import (
+	"context"
 	"time"
 
+	"github.com/weblazy/easy/ebreaker"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/etls"
 	"github.com/weblazy/easy/etrace"
+	"github.com/weblazy/easy/grpc/grpc_client/consistent_hash"
 	"github.com/weblazy/easy/grpc/grpc_client/interceptor"
+	"github.com/weblazy/easy/retry"
+	"go.uber.org/zap"
 	"google.golang.org/grpc"
 	"google.golang.org/grpc/balancer/roundrobin"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/keepalive"
 )
 
@@ -19,7 +27,8 @@ type Config struct {
 	Name             string
 	Debug            bool          // 是否开启调试，默认不开启, 开启可以打印请求日志
 	Addr             string        // 连接地址，直连为 127.0.0.1:9090，服务发现为 nacos:///appname
-	BalancerName     string        // 负载均衡方式，默认 round_robin
+	BalancerName     string        // 负载均衡方式，默认 round_robin, consistent_hash 为一致性哈希
+	HashKey          string        // consistent_hash 使用的 metadata 或透传参数 key, 默认 x-pass-uid, 没有时使用 round_robin
 	DialTimeout      time.Duration // 连接超时，默认3s
 	ReadTimeout      time.Duration // 读超时，默认1s
 	SlowLogThreshold time.Duration // 慢日志记录的阈值，默认600ms
@@ -37,6 +46,23 @@ type Config struct {
 	// Deprecated: not affect anything
 	EnableSkyWalking bool // 是否额外开启 skywalking, 默认不开启
 
+	CertFile          string        // mTLS 客户端证书文件, 默认为空
+	KeyFile           string        // mTLS 客户端私钥文件
+	CaFile            string        // CA 证书文件, 配置后开启 TLS 校验服务端证书, 默认为空
+	ServerName        string        // 校验的服务端证书名称, 默认使用连接地址
+	TLSReloadInterval time.Duration // 证书文件变更检查间隔, 证书轮换后自动重新加载, 默认 1m
+
+	EnableBreakerInterceptor bool             // 是否开启熔断, 默认关闭
+	Breaker                  *ebreaker.Config // 熔断配置, 默认 10s 窗口内请求数超过 100 时开始按成功率丢弃请求
+
+	EnableRetryInterceptor bool                   // 是否开启重试, 默认关闭
+	Retry                  *interceptor.RetryConf // 重试配置, 默认只重试 Unavailable, 最多重试 2 次, 需要配置允许重试的幂等方法
+
+	EnableHedgeInterceptor bool                   // 是否开启对冲请求, 默认关闭
+	Hedge                  *interceptor.HedgeConf // 对冲请求配置, 需要配置开启对冲的幂等方法, 默认等待 p95 耗时后最多发送 1 个对冲请求
+
+	Replay *interceptor.ReplayConf // 录制回放配置, 用于单测, Mode 为 record 时录制下游响应, replay 时从 golden 文件返回响应, 默认不开启
+
 	KeepAlive   *keepalive.ClientParameters
 	DialOptions []grpc.DialOption
 }
@@ -63,9 +89,43 @@ func DefaultConfig() *Config {
 		},
 		EnableServiceConfig: false,
 		Addr:                "127.0.0.1:9090",
+		TLSReloadInterval:   etls.DefaultReloadInterval,
+		Breaker:             ebreaker.DefaultConfig(),
+		Retry: &interceptor.RetryConf{
+			Backoff: retry.Config{
+				Policy:              retry.PolicyExponential,
+				InitialInterval:     time.Millisecond * 50,
+				RandomizationFactor: 0.2,
+				Multiplier:          2,
+				MaxInterval:         time.Second,
+				MaxRetries:          2,
+			},
+			Codes: []string{codes.Unavailable.String()},
+		},
+		Hedge: &interceptor.HedgeConf{
+			MaxHedges:   1,
+			BudgetRatio: 0.1,
+			Codes:       []string{codes.Unavailable.String(), codes.DeadlineExceeded.String()},
+		},
 	}
 }
 
+// TLSConfig 证书配置
+func (config *Config) TLSConfig() etls.Config {
+	return etls.Config{
+		CertFile:       config.CertFile,
+		KeyFile:        config.KeyFile,
+		CaFile:         config.CaFile,
+		ServerName:     config.ServerName,
+		ReloadInterval: config.TLSReloadInterval,
+	}
+}
+
+// IsReplay 是否为回放模式, 回放时不需要连接下游
+func (config *Config) IsReplay() bool {
+	return config.Replay != nil && config.Replay.Mode == interceptor.ReplayModeReplay
+}
+
 func (config *Config) BuildDialOptions() {
 	// 最先执行trace
 	if config.EnableTraceInterceptor {
@@ -75,6 +135,11 @@ func (config *Config) BuildDialOptions() {
 	// 透传公共参数
 	config.DialOptions = append(config.DialOptions, grpc.WithChainUnaryInterceptor(interceptor.GrpcHeaderCarrierInterceptor()))
 
+	// 一致性哈希从 metadata 或透传参数中读取 hash key
+	if config.BalancerName == consistent_hash.Name {
+		config.DialOptions = append(config.DialOptions, grpc.WithChainUnaryInterceptor(consistent_hash.HashKeyUnaryClientInterceptor(config.HashKey)))
+	}
+
 	// 其次执行，自定义header头，这样才能赋值到ctx里
 	// options = append(options, WithDialOption(grpc.WithChainUnaryInterceptor(customHeader(transport.CustomContextKeys()))))
 
@@ -85,10 +150,39 @@ func (config *Config) BuildDialOptions() {
 		config.DialOptions = append(config.DialOptions, grpc.WithChainUnaryInterceptor(interceptor.TimeoutInterceptor(config.ReadTimeout)))
 	}
 
+	// 熔断在重试之前, 熔断器打开时不会重试
+	if config.EnableBreakerInterceptor {
+		target := config.Name
+		if target == "" {
+			target = config.Addr
+		}
+		config.DialOptions = append(config.DialOptions, grpc.WithChainUnaryInterceptor(interceptor.BreakerUnaryClientInterceptor(target, config.Breaker)))
+	}
+
+	// 重试在整体超时之内, 每次请求都会经过 metric
+	if config.EnableRetryInterceptor && config.Retry != nil {
+		config.DialOptions = append(config.DialOptions, grpc.WithChainUnaryInterceptor(interceptor.RetryUnaryClientInterceptor(config.Retry)))
+	}
+
+	// 对冲请求在重试之内, 每个对冲请求都会经过 metric
+	// 一致性哈希时对冲请求会发送到同一个后端, 不开启对冲
+	if config.EnableHedgeInterceptor && config.Hedge != nil {
+		if config.BalancerName == consistent_hash.Name {
+			elog.WarnCtx(context.Background(), "grpc client hedge is disabled with consistent_hash balancer", elog.FieldName(config.Name), zap.String("addr", config.Addr))
+		} else {
+			config.DialOptions = append(config.DialOptions, grpc.WithChainUnaryInterceptor(interceptor.HedgeUnaryClientInterceptor(config.Hedge)))
+		}
+	}
+
 	if config.EnableMetricInterceptor {
 		config.DialOptions = append(config.DialOptions,
 			grpc.WithChainUnaryInterceptor(interceptor.MetricUnaryClientInterceptor(config.MetricSuccessCodes)),
 		)
 	}
 
+	// 录制回放在最内层, 回放时不请求下游
+	if config.Replay != nil && config.Replay.Mode != "" {
+		config.DialOptions = append(config.DialOptions, grpc.WithChainUnaryInterceptor(interceptor.ReplayUnaryClientInterceptor(config.Replay)))
+	}
+
 }
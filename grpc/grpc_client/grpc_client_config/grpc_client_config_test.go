@@ -0,0 +1,29 @@
+package grpc_client_config
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/grpc/grpc_client/consistent_hash"
+)
+
+func TestConfig_BuildDialOptions(t *testing.T) {
+	config := DefaultConfig()
+	config.EnableHedgeInterceptor = true
+	config.Hedge.Methods = []string{"*"}
+	config.BuildDialOptions()
+	hedged := len(config.DialOptions)
+
+	// 一致性哈希时不开启对冲请求, 增加 hash key 拦截器
+	config = DefaultConfig()
+	config.EnableHedgeInterceptor = true
+	config.Hedge.Methods = []string{"*"}
+	config.BalancerName = consistent_hash.Name
+	config.BuildDialOptions()
+	assert.Equal(t, hedged, len(config.DialOptions))
+
+	config.DialOptions = nil
+	config.EnableHedgeInterceptor = false
+	config.BuildDialOptions()
+	assert.Equal(t, hedged, len(config.DialOptions))
+}
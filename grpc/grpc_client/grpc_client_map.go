@@ -0,0 +1,48 @@
+package grpc_client
+
+import (
+	"sync"
+
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/grpc/grpc_client/grpc_client_config"
+)
+
+var (
+	GrpcClientMap sync.Map
+	grpcClientMu  sync.Mutex
+)
+
+// GetGrpcClient 按照配置 key 获取 grpc 客户端, 不存在时从 econfig.GlobalViper 读取配置创建
+// 连接失败时不缓存, 返回的客户端通过 Error() 获取错误
+func GetGrpcClient(name string) *GrpcClient {
+	if v, ok := GrpcClientMap.Load(name); ok {
+		return v.(*GrpcClient)
+	}
+	grpcClientMu.Lock()
+	defer grpcClientMu.Unlock()
+	if v, ok := GrpcClientMap.Load(name); ok {
+		return v.(*GrpcClient)
+	}
+
+	conf := grpc_client_config.DefaultConfig()
+	econfig.GlobalViper.UnmarshalKey(name, conf)
+	if conf.Name == "" {
+		conf.Name = name
+	}
+	client := NewGrpcClient(conf)
+	if client.Error() != nil {
+		return client
+	}
+
+	GrpcClientMap.Store(name, client)
+	ecomponent.Register(ecomponent.KindGrpcClient, name, closes.ClientPriority, func() {
+		GrpcClientMap.Delete(name)
+		if err := client.Close(); err != nil {
+			elog.ErrorCtx(emptyCtx, "close grpc client", elog.FieldName(name), elog.FieldError(err))
+		}
+	})
+	return client
+}
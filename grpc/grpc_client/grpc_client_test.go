@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/smartystreets/goconvey/convey"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/econfig/eviper"
 )
 
 func TestNewGrpcClient(t *testing.T) {
@@ -16,3 +19,25 @@ func TestNewGrpcClient(t *testing.T) {
 		// convey.So(resp, convey.ShouldNotBeNil)
 	})
 }
+
+func TestGetGrpcClient(t *testing.T) {
+	convey.Convey("TestGetGrpcClient", t, func() {
+		econfig.GlobalViper = eviper.NewViperFromString(`
+[userGrpc]
+Addr = "127.0.0.1:9090"
+EnableBlock = false
+`)
+		client := GetGrpcClient("userGrpc")
+		convey.So(client.Error(), convey.ShouldBeNil)
+		convey.So(client.Target(), convey.ShouldEqual, "127.0.0.1:9090")
+		convey.So(GetGrpcClient("userGrpc"), convey.ShouldEqual, client)
+
+		var found bool
+		for _, c := range ecomponent.List() {
+			if c.Kind == ecomponent.KindGrpcClient && c.Name == "userGrpc" {
+				found = true
+			}
+		}
+		convey.So(found, convey.ShouldBeTrue)
+	})
+}
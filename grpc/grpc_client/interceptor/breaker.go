@@ -0,0 +1,53 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/weblazy/easy/ebreaker"
+	"github.com/weblazy/easy/elog"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// BreakerError 熔断器打开时返回的错误, 转换为 codes.Unavailable
+// 可以使用 errors.Is(err, ebreaker.ErrNotAllowed) 判断
+type BreakerError struct {
+	Method string
+}
+
+func (e *BreakerError) Error() string {
+	return ebreaker.ErrNotAllowed.Error() + ": " + e.Method
+}
+
+func (e *BreakerError) GRPCStatus() *status.Status {
+	return status.New(codes.Unavailable, e.Error())
+}
+
+func (e *BreakerError) Is(target error) bool {
+	return target == ebreaker.ErrNotAllowed
+}
+
+// IsBreakerFailure 服务端异常时记为失败, 业务错误和客户端参数错误不影响熔断
+func IsBreakerFailure(err error) bool {
+	switch status.Code(err) {
+	case codes.Unknown, codes.DeadlineExceeded, codes.ResourceExhausted, codes.Internal, codes.Unavailable, codes.DataLoss:
+		return true
+	}
+	return false
+}
+
+// BreakerUnaryClientInterceptor 按照方法熔断, 每个 client 独立统计, target 用于区分监控指标
+func BreakerUnaryClientInterceptor(target string, config *ebreaker.Config) grpc.UnaryClientInterceptor {
+	group := ebreaker.NewGroup("grpc", target, config)
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		breaker := group.Get(method)
+		if err := breaker.Allow(); err != nil {
+			elog.WarnCtx(ctx, "grpc client circuit breaker open", elog.FieldMethod(method), elog.FieldName(target))
+			return &BreakerError{Method: method}
+		}
+		err := invoker(ctx, method, req, reply, cc, opts...)
+		breaker.Mark(!IsBreakerFailure(err))
+		return err
+	}
+}
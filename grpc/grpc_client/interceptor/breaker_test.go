@@ -0,0 +1,43 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/ebreaker"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestBreakerUnaryClientInterceptor(t *testing.T) {
+	mw := BreakerUnaryClientInterceptor("user", &ebreaker.Config{Request: 10})
+	var calls int
+	invoker := func(code codes.Code) grpc.UnaryInvoker {
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			calls++
+			return status.Error(code, code.String())
+		}
+	}
+
+	// 业务错误不会触发熔断
+	for i := 0; i < 100; i++ {
+		err := mw(context.Background(), "/user.UserService/GetUserInfo", nil, nil, nil, invoker(codes.FailedPrecondition))
+		assert.Equal(t, codes.FailedPrecondition, status.Code(err))
+	}
+	assert.Equal(t, 100, calls)
+
+	// 服务端异常触发熔断
+	var rejected int
+	for i := 0; i < 100; i++ {
+		err := mw(context.Background(), "/user.UserService/Login", nil, nil, nil, invoker(codes.Unavailable))
+		if errors.Is(err, ebreaker.ErrNotAllowed) {
+			rejected++
+			assert.Equal(t, codes.Unavailable, status.Code(err))
+		}
+	}
+	assert.Greater(t, rejected, 50)
+	assert.Equal(t, 200-rejected, calls)
+}
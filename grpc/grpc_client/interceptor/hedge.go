@@ -0,0 +1,262 @@
+package interceptor
+
+import (
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/fmetric"
+	"github.com/weblazy/easy/grpc/grpc_method"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// DefaultHedgeDelay 未配置等待时间且没有耗时数据时的等待时间
+	DefaultHedgeDelay = time.Millisecond * 100
+	// hedgeDelayRefresh p95 耗时的刷新间隔
+	hedgeDelayRefresh = time.Second * 10
+	// hedgeQuantile 未配置等待时间时使用的耗时分位
+	hedgeQuantile = 0.95
+)
+
+var (
+	// ClientHedgeCounter 对冲请求, event 为 sent 发送的对冲请求, won 对冲请求先于原请求返回
+	ClientHedgeCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_client_hedge_total",
+			Help: "Total number of hedged RPCs sent and won on the client.",
+		}, []string{"grpc_service", "grpc_method", "event"})
+)
+
+func init() {
+	prometheus.MustRegister(ClientHedgeCounter)
+}
+
+// HedgeConf 对冲请求配置
+type HedgeConf struct {
+	Methods     []string      // 开启对冲的幂等方法, 完整方法名 /package.Service/Method, 支持 /package.Service/* 和 * 通配
+	Delay       time.Duration // 发送对冲请求前的等待时间, 为 0 时使用客户端耗时直方图的 p95, 需要开启 metric
+	MaxHedges   int           // 最多额外发送的对冲请求数, 默认 1
+	BudgetRatio float64       // 对冲请求占请求总数的比例上限, 默认 0.1
+	Codes       []string      // 返回这些 grpc 状态码时继续等待其他请求的结果, 默认 Unavailable, DeadlineExceeded
+}
+
+// DefaultHedgeCodes 未配置 HedgeConf.Codes 时继续等待其他请求的状态码
+var DefaultHedgeCodes = []string{codes.Unavailable.String(), codes.DeadlineExceeded.String()}
+
+// hedgeBudget 对冲请求预算, 每个请求增加 ratio 个令牌, 发送对冲请求消耗 1 个令牌
+type hedgeBudget struct {
+	mu     sync.Mutex
+	ratio  float64
+	tokens float64
+}
+
+// maxHedgeTokens 令牌上限, 避免长时间低负载后突发大量对冲请求
+const maxHedgeTokens = 10
+
+func (b *hedgeBudget) deposit() {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.tokens += b.ratio
+	if b.tokens > maxHedgeTokens {
+		b.tokens = maxHedgeTokens
+	}
+}
+
+func (b *hedgeBudget) withdraw() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+type hedgeDelay struct {
+	delay     time.Duration
+	refreshAt time.Time
+}
+
+// hedger 计算方法的对冲等待时间
+type hedger struct {
+	config *HedgeConf
+	mu     sync.Mutex
+	delays map[string]hedgeDelay
+}
+
+func (h *hedger) delay(method string) time.Duration {
+	if h.config.Delay > 0 {
+		return h.config.Delay
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if d, ok := h.delays[method]; ok && time.Now().Before(d.refreshAt) {
+		return d.delay
+	}
+	delay, ok := observedLatency(method, hedgeQuantile)
+	if !ok {
+		delay = DefaultHedgeDelay
+	}
+	h.delays[method] = hedgeDelay{delay: delay, refreshAt: time.Now().Add(hedgeDelayRefresh)}
+	return delay
+}
+
+// observedLatency 从 grpc_prometheus 客户端耗时直方图中计算方法的耗时分位
+func observedLatency(fullMethod string, quantile float64) (time.Duration, bool) {
+	service, method := fmetric.SplitGrpcMethodName(fullMethod)
+	ch := make(chan prometheus.Metric, 64)
+	go func() {
+		grpc_prometheus.DefaultClientMetrics.Collect(ch)
+		close(ch)
+	}()
+
+	var count uint64
+	buckets := make(map[float64]uint64)
+	for m := range ch {
+		var metric dto.Metric
+		if m.Write(&metric) != nil || metric.Histogram == nil || !matchLabels(metric.Label, service, method) {
+			continue
+		}
+		count += metric.Histogram.GetSampleCount()
+		for _, b := range metric.Histogram.Bucket {
+			buckets[b.GetUpperBound()] += b.GetCumulativeCount()
+		}
+	}
+	if count == 0 {
+		return 0, false
+	}
+
+	bounds := make([]float64, 0, len(buckets))
+	for bound := range buckets {
+		bounds = append(bounds, bound)
+	}
+	sort.Float64s(bounds)
+	rank := quantile * float64(count)
+	var lowerBound float64
+	var lowerCount uint64
+	for _, bound := range bounds {
+		if float64(buckets[bound]) >= rank {
+			// 桶内线性插值
+			inBucket := float64(buckets[bound] - lowerCount)
+			seconds := bound
+			if inBucket > 0 {
+				seconds = lowerBound + (bound-lowerBound)*(rank-float64(lowerCount))/inBucket
+			}
+			return time.Duration(seconds * float64(time.Second)), true
+		}
+		lowerBound, lowerCount = bound, buckets[bound]
+	}
+	// 超过最大的桶, 使用最大桶的上限
+	return time.Duration(lowerBound * float64(time.Second)), true
+}
+
+func matchLabels(labels []*dto.LabelPair, service, method string) bool {
+	var matched int
+	for _, l := range labels {
+		switch l.GetName() {
+		case "grpc_service":
+			if l.GetValue() != service {
+				return false
+			}
+			matched++
+		case "grpc_method":
+			if l.GetValue() != method {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == 2
+}
+
+type hedgeResult struct {
+	reply interface{}
+	err   error
+	hedge bool
+}
+
+// HedgeUnaryClientInterceptor 对冲请求, 请求超过等待时间未返回时再发送一个请求, 使用最先返回的结果并取消其他请求
+// 使用 round_robin 负载均衡时对冲请求会发送到其他连接, 返回 HedgeConf.Codes 中的状态码时继续等待其他请求的结果
+func HedgeUnaryClientInterceptor(config *HedgeConf) grpc.UnaryClientInterceptor {
+	methods := grpc_method.NewMethodSet(config.Methods...)
+	hedgeCodes := config.Codes
+	if len(hedgeCodes) == 0 {
+		hedgeCodes = DefaultHedgeCodes
+	}
+	continueCodes := ParseCodes(hedgeCodes)
+	maxHedges := config.MaxHedges
+	if maxHedges <= 0 {
+		maxHedges = 1
+	}
+	ratio := config.BudgetRatio
+	if ratio <= 0 {
+		ratio = 0.1
+	}
+	budget := &hedgeBudget{ratio: ratio}
+	h := &hedger{config: config, delays: make(map[string]hedgeDelay)}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		msg, ok := reply.(proto.Message)
+		if !ok || !methods.Has(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+		budget.deposit()
+
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+
+		results := make(chan hedgeResult, 1+maxHedges)
+		// 每个请求使用独立的 reply, 被取消的请求不会修改返回结果
+		send := func(r proto.Message, hedge bool) {
+			err := invoker(ctx, method, req, r, cc, opts...)
+			results <- hedgeResult{reply: r, err: err, hedge: hedge}
+		}
+		go send(proto.Clone(msg), false)
+
+		service, shortMethod := fmetric.SplitGrpcMethodName(method)
+		delay := h.delay(method)
+		timer := time.NewTimer(delay)
+		defer timer.Stop()
+		pending, hedges := 1, 0
+		var lastErr error
+		for {
+			select {
+			case <-timer.C:
+				if hedges < maxHedges && budget.withdraw() {
+					hedges++
+					pending++
+					ClientHedgeCounter.WithLabelValues(service, shortMethod, "sent").Inc()
+					elog.InfoCtx(ctx, "grpc client hedge", elog.FieldMethod(method), zap.Int("hedge", hedges), zap.Duration("delay", delay))
+					go send(proto.Clone(msg), true)
+					timer.Reset(delay)
+				}
+			case res := <-results:
+				pending--
+				if res.err == nil || !continueCodes[status.Code(res.err)] || pending == 0 {
+					if res.hedge && res.err == nil {
+						ClientHedgeCounter.WithLabelValues(service, shortMethod, "won").Inc()
+					}
+					proto.Reset(msg)
+					proto.Merge(msg, res.reply.(proto.Message))
+					return res.err
+				}
+				lastErr = res.err
+			case <-ctx.Done():
+				if lastErr != nil {
+					return lastErr
+				}
+				return status.FromContextError(ctx.Err()).Err()
+			}
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package interceptor
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	grpc_prometheus "github.com/grpc-ecosystem/go-grpc-prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestHedgeUnaryClientInterceptor(t *testing.T) {
+	mw := HedgeUnaryClientInterceptor(&HedgeConf{
+		Methods:     []string{"/grpc.health.v1.Health/Check"},
+		Delay:       10 * time.Millisecond,
+		BudgetRatio: 1,
+	})
+	const method = "/grpc.health.v1.Health/Check"
+
+	// 第一个请求很慢时使用对冲请求的结果, 并取消第一个请求
+	var calls int32
+	canceled := make(chan struct{})
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if atomic.AddInt32(&calls, 1) == 1 {
+			reply.(*healthpb.HealthCheckResponse).Status = healthpb.HealthCheckResponse_NOT_SERVING
+			<-ctx.Done()
+			close(canceled)
+			return ctx.Err()
+		}
+		reply.(*healthpb.HealthCheckResponse).Status = healthpb.HealthCheckResponse_SERVING
+		return nil
+	}
+	won := testutil.ToFloat64(ClientHedgeCounter.WithLabelValues("grpc.health.v1.Health", "Check", "won"))
+	reply := &healthpb.HealthCheckResponse{}
+	assert.Nil(t, mw(context.Background(), method, &healthpb.HealthCheckRequest{}, reply, nil, invoker))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, reply.Status)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+	assert.Equal(t, won+1, testutil.ToFloat64(ClientHedgeCounter.WithLabelValues("grpc.health.v1.Health", "Check", "won")))
+	select {
+	case <-canceled:
+	case <-time.After(time.Second):
+		t.Fatal("slow request is not canceled")
+	}
+
+	// 请求在等待时间内返回时不发送对冲请求
+	atomic.StoreInt32(&calls, 1)
+	reply = &healthpb.HealthCheckResponse{}
+	assert.Nil(t, mw(context.Background(), method, &healthpb.HealthCheckRequest{}, reply, nil, invoker))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, reply.Status)
+	assert.Equal(t, int32(2), atomic.LoadInt32(&calls))
+}
+
+func TestHedgeUnaryClientInterceptor_codes(t *testing.T) {
+	const method = "/grpc.health.v1.Health/Check"
+	// 第一个请求在对冲请求发送后返回 code, 对冲请求再过 20ms 成功
+	newInvoker := func(code codes.Code) grpc.UnaryInvoker {
+		var calls int32
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			if atomic.AddInt32(&calls, 1) == 1 {
+				time.Sleep(20 * time.Millisecond)
+				return status.Error(code, code.String())
+			}
+			time.Sleep(20 * time.Millisecond)
+			return nil
+		}
+	}
+
+	// 默认 Unavailable 时等待对冲请求的结果
+	mw := HedgeUnaryClientInterceptor(&HedgeConf{Methods: []string{"*"}, Delay: 10 * time.Millisecond, BudgetRatio: 1})
+	assert.Nil(t, mw(context.Background(), method, &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{}, nil, newInvoker(codes.Unavailable)))
+	err := mw(context.Background(), method, &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{}, nil, newInvoker(codes.Internal))
+	assert.Equal(t, codes.Internal, status.Code(err))
+
+	// 配置的状态码
+	mw = HedgeUnaryClientInterceptor(&HedgeConf{Methods: []string{"*"}, Delay: 10 * time.Millisecond, BudgetRatio: 1, Codes: []string{"Internal"}})
+	assert.Nil(t, mw(context.Background(), method, &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{}, nil, newInvoker(codes.Internal)))
+	err = mw(context.Background(), method, &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{}, nil, newInvoker(codes.Unavailable))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
+
+func TestHedgeUnaryClientInterceptor_budget(t *testing.T) {
+	mw := HedgeUnaryClientInterceptor(&HedgeConf{
+		Methods:     []string{"*"},
+		Delay:       time.Millisecond,
+		BudgetRatio: 0.5,
+	})
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		atomic.AddInt32(&calls, 1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	}
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, mw(context.Background(), "/grpc.health.v1.Health/Watch", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{}, nil, invoker))
+	}
+	// 每 2 个请求最多 1 个对冲请求
+	assert.LessOrEqual(t, atomic.LoadInt32(&calls), int32(15))
+}
+
+func TestObservedLatency(t *testing.T) {
+	grpc_prometheus.EnableClientHandlingTimeHistogram()
+	method := fmt.Sprintf("/user.UserService/HedgeLatency%d", time.Now().UnixNano())
+	_, ok := observedLatency(method, 0.95)
+	assert.False(t, ok)
+
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		time.Sleep(15 * time.Millisecond)
+		return nil
+	}
+	for i := 0; i < 10; i++ {
+		assert.Nil(t, grpc_prometheus.UnaryClientInterceptor(context.Background(), method, nil, nil, nil, invoker))
+	}
+	latency, ok := observedLatency(method, 0.95)
+	assert.True(t, ok)
+	assert.Greater(t, latency, 10*time.Millisecond)
+	assert.LessOrEqual(t, latency, 50*time.Millisecond)
+}
@@ -3,8 +3,10 @@ package interceptor
 import (
 	"context"
 	"net/http"
+	"sync/atomic"
 	"time"
 
+	"github.com/weblazy/easy/code_err"
 	"github.com/weblazy/easy/ecodes"
 	"github.com/weblazy/easy/elog"
 	"github.com/weblazy/easy/transport"
@@ -41,6 +43,7 @@ func LoggerUnaryClientInterceptor(config *LogConf) grpc.UnaryClientInterceptor {
 
 		transport.CustomKeysMapPropagator.Inject(ctx, transport.GrpcHeaderCarrier(md))
 		ctx = metadata.NewOutgoingContext(ctx, md)
+		ctx, attempts := withAttempts(ctx)
 
 		err := invoker(ctx, method, req, res, cc, opts...)
 		duration := time.Since(beg)
@@ -56,6 +59,10 @@ func LoggerUnaryClientInterceptor(config *LogConf) grpc.UnaryClientInterceptor {
 			elog.FieldCost(duration),
 			elog.FieldName(cc.Target()),
 		)
+		// 开启重试的方法记录请求次数
+		if n := atomic.LoadInt32(attempts); n > 0 {
+			fields = append(fields, zap.Int32("attempts", n))
+		}
 
 		span := trace.SpanFromContext(ctx)
 		// add custom metadata to trace fields
@@ -82,6 +89,9 @@ func LoggerUnaryClientInterceptor(config *LogConf) grpc.UnaryClientInterceptor {
 
 		if err != nil {
 			fields = append(fields, elog.FieldEvent("error"), elog.FieldError(err))
+			if codeErr, ok := code_err.FromError(err); ok {
+				fields = append(fields, zap.Int64("biz_code", codeErr.Code))
+			}
 			// 只记录系统级别错误
 			if httpStatusCode >= http.StatusInternalServerError {
 				// 只记录系统级别错误
@@ -0,0 +1,267 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync"
+
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/fmetric"
+	spb "google.golang.org/genproto/googleapis/rpc/status"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+const (
+	// ReplayModeRecord 请求下游并把请求和响应写入 golden 文件
+	ReplayModeRecord = "record"
+	// ReplayModeReplay 从 golden 文件中返回响应, 不请求下游
+	ReplayModeReplay = "replay"
+	// DefaultReplayDir 默认 golden 文件目录
+	DefaultReplayDir = "testdata/grpc"
+)
+
+// ErrReplayUnmatched 回放时没有匹配的录制记录
+var ErrReplayUnmatched = errors.New("grpc replay: no recorded response matched")
+
+// ReplayConf 录制回放配置, 用于单测中替代下游服务
+type ReplayConf struct {
+	Mode        string   // record 或 replay, 为空时不开启
+	Dir         string   // golden 文件目录, 每个方法一个文件 Dir/package.Service/Method.json, 默认 testdata/grpc
+	MatchFields []string // 回放时比较的请求字段, 使用 proto json 名称, 为空时比较整个请求
+}
+
+// ReplayRecord golden 文件中的一条记录
+type ReplayRecord struct {
+	Method   string          `json:"method"`
+	Request  json.RawMessage `json:"request"`
+	Response json.RawMessage `json:"response,omitempty"`
+	Code     string          `json:"code"`
+	Message  string          `json:"message,omitempty"`
+	Status   json.RawMessage `json:"status,omitempty"` // 带 detail 的错误, 如 code_err 业务错误
+}
+
+// ReplayUnmatchedError 回放时没有匹配的记录, 转换为 codes.Unimplemented
+// 可以使用 errors.Is(err, ErrReplayUnmatched) 判断
+type ReplayUnmatchedError struct {
+	Method  string
+	File    string
+	Request string
+}
+
+func (e *ReplayUnmatchedError) Error() string {
+	return fmt.Sprintf("%s: method %s, request %s, file %s", ErrReplayUnmatched.Error(), e.Method, e.Request, e.File)
+}
+
+func (e *ReplayUnmatchedError) GRPCStatus() *status.Status {
+	return status.New(codes.Unimplemented, e.Error())
+}
+
+func (e *ReplayUnmatchedError) Is(target error) bool {
+	return target == ErrReplayUnmatched
+}
+
+// replayer 读写 golden 文件, 同一个文件的读写加锁
+type replayer struct {
+	config *ReplayConf
+	mu     sync.Mutex
+	cache  map[string][]ReplayRecord
+}
+
+func (r *replayer) file(method string) string {
+	service, shortMethod := fmetric.SplitGrpcMethodName(method)
+	return filepath.Join(r.config.Dir, service, shortMethod+".json")
+}
+
+// load 读取方法的记录, 回放时缓存文件内容
+func (r *replayer) load(file string) ([]ReplayRecord, error) {
+	if records, ok := r.cache[file]; ok {
+		return records, nil
+	}
+	var records []ReplayRecord
+	data, err := os.ReadFile(file)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if len(data) > 0 {
+		if err := json.Unmarshal(data, &records); err != nil {
+			return nil, fmt.Errorf("grpc replay: parse %s: %w", file, err)
+		}
+	}
+	r.cache[file] = records
+	return records, nil
+}
+
+// record 写入一条记录, 请求相同的记录会被覆盖
+func (r *replayer) record(method string, req, reply proto.Message, err error) error {
+	reqJSON, marshalErr := protojson.Marshal(req)
+	if marshalErr != nil {
+		return marshalErr
+	}
+	st := status.Convert(err)
+	record := ReplayRecord{
+		Method:  method,
+		Request: reqJSON,
+		Code:    st.Code().String(),
+		Message: st.Message(),
+	}
+	if err == nil {
+		if record.Response, marshalErr = protojson.Marshal(reply); marshalErr != nil {
+			return marshalErr
+		}
+	} else if len(st.Details()) > 0 {
+		if record.Status, marshalErr = protojson.Marshal(st.Proto()); marshalErr != nil {
+			return marshalErr
+		}
+	}
+
+	file := r.file(method)
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	records, loadErr := r.load(file)
+	if loadErr != nil {
+		return loadErr
+	}
+	replaced := false
+	for i := range records {
+		if jsonEqual(records[i].Request, record.Request) {
+			records[i] = record
+			replaced = true
+		}
+	}
+	if !replaced {
+		records = append(records, record)
+	}
+	r.cache[file] = records
+
+	// MarshalIndent 会重新格式化 RawMessage, 文件内容稳定
+	data, marshalErr := json.MarshalIndent(records, "", "  ")
+	if marshalErr != nil {
+		return marshalErr
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(file, append(data, '\n'), 0o644)
+}
+
+// replay 返回匹配的记录, 没有匹配时返回 ReplayUnmatchedError
+func (r *replayer) replay(method string, req, reply proto.Message) error {
+	file := r.file(method)
+	r.mu.Lock()
+	records, err := r.load(file)
+	r.mu.Unlock()
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+
+	reqJSON, err := protojson.Marshal(req)
+	if err != nil {
+		return status.Error(codes.Internal, err.Error())
+	}
+	for _, record := range records {
+		if record.Method != method {
+			continue
+		}
+		recorded := req.ProtoReflect().New().Interface()
+		if err := protojson.Unmarshal(record.Request, recorded); err != nil {
+			return status.Errorf(codes.Internal, "grpc replay: parse request in %s: %v", file, err)
+		}
+		if !r.match(req, recorded) {
+			continue
+		}
+		if len(record.Status) > 0 {
+			st := &spb.Status{}
+			if err := protojson.Unmarshal(record.Status, st); err != nil {
+				return status.Errorf(codes.Internal, "grpc replay: parse status in %s: %v", file, err)
+			}
+			return status.FromProto(st).Err()
+		}
+		for c := range ParseCodes([]string{record.Code}) {
+			if c != codes.OK {
+				return status.Error(c, record.Message)
+			}
+		}
+		proto.Reset(reply)
+		if len(record.Response) == 0 {
+			return nil
+		}
+		if err := protojson.Unmarshal(record.Response, reply); err != nil {
+			return status.Errorf(codes.Internal, "grpc replay: parse response in %s: %v", file, err)
+		}
+		return nil
+	}
+	return &ReplayUnmatchedError{Method: method, File: file, Request: string(reqJSON)}
+}
+
+// match 没有配置 MatchFields 时比较整个请求, 否则只比较配置的字段
+func (r *replayer) match(req, recorded proto.Message) bool {
+	if len(r.config.MatchFields) == 0 {
+		return proto.Equal(req, recorded)
+	}
+	actual, err1 := protoToMap(req)
+	expected, err2 := protoToMap(recorded)
+	if err1 != nil || err2 != nil {
+		return false
+	}
+	for _, field := range r.config.MatchFields {
+		if !reflect.DeepEqual(actual[field], expected[field]) {
+			return false
+		}
+	}
+	return true
+}
+
+func protoToMap(m proto.Message) (map[string]interface{}, error) {
+	data, err := protojson.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]interface{})
+	return result, json.Unmarshal(data, &result)
+}
+
+func jsonEqual(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	if json.Unmarshal(a, &va) != nil || json.Unmarshal(b, &vb) != nil {
+		return false
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+// ReplayUnaryClientInterceptor 录制回放, record 模式请求下游后写入 golden 文件, replay 模式直接从 golden 文件返回响应
+// 作为最内层的拦截器, 回放时不会建立网络请求
+func ReplayUnaryClientInterceptor(config *ReplayConf) grpc.UnaryClientInterceptor {
+	if config.Dir == "" {
+		config.Dir = DefaultReplayDir
+	}
+	r := &replayer{config: config, cache: make(map[string][]ReplayRecord)}
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		reqMsg, ok1 := req.(proto.Message)
+		replyMsg, ok2 := reply.(proto.Message)
+		if !ok1 || !ok2 {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		switch config.Mode {
+		case ReplayModeReplay:
+			return r.replay(method, reqMsg, replyMsg)
+		case ReplayModeRecord:
+			err := invoker(ctx, method, req, reply, cc, opts...)
+			if recordErr := r.record(method, reqMsg, replyMsg, err); recordErr != nil {
+				elog.ErrorCtx(ctx, "grpc record", elog.FieldMethod(method), elog.FieldError(recordErr))
+			}
+			return err
+		}
+		return invoker(ctx, method, req, reply, cc, opts...)
+	}
+}
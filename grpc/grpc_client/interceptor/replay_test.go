@@ -0,0 +1,66 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/status"
+)
+
+func TestReplayUnaryClientInterceptor(t *testing.T) {
+	dir := t.TempDir()
+	const method = "/grpc.health.v1.Health/Check"
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		if req.(*healthpb.HealthCheckRequest).Service == "down" {
+			return status.Error(codes.Unavailable, "down")
+		}
+		reply.(*healthpb.HealthCheckResponse).Status = healthpb.HealthCheckResponse_SERVING
+		return nil
+	}
+
+	// 录制
+	record := ReplayUnaryClientInterceptor(&ReplayConf{Mode: ReplayModeRecord, Dir: dir})
+	reply := &healthpb.HealthCheckResponse{}
+	assert.Nil(t, record(context.Background(), method, &healthpb.HealthCheckRequest{Service: "user"}, reply, nil, invoker))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, reply.Status)
+	err := record(context.Background(), method, &healthpb.HealthCheckRequest{Service: "down"}, &healthpb.HealthCheckResponse{}, nil, invoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	// 相同请求覆盖之前的记录
+	assert.Nil(t, record(context.Background(), method, &healthpb.HealthCheckRequest{Service: "user"}, &healthpb.HealthCheckResponse{}, nil, invoker))
+	data, err := os.ReadFile(filepath.Join(dir, "grpc.health.v1.Health", "Check.json"))
+	assert.Nil(t, err)
+	assert.Contains(t, string(data), `"code": "Unavailable"`)
+
+	// 回放不请求下游
+	noNetwork := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		t.Fatal("replay should not invoke")
+		return nil
+	}
+	replay := ReplayUnaryClientInterceptor(&ReplayConf{Mode: ReplayModeReplay, Dir: dir})
+	reply = &healthpb.HealthCheckResponse{}
+	assert.Nil(t, replay(context.Background(), method, &healthpb.HealthCheckRequest{Service: "user"}, reply, nil, noNetwork))
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, reply.Status)
+
+	err = replay(context.Background(), method, &healthpb.HealthCheckRequest{Service: "down"}, &healthpb.HealthCheckResponse{}, nil, noNetwork)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, "down", status.Convert(err).Message())
+
+	// 没有匹配的记录
+	err = replay(context.Background(), method, &healthpb.HealthCheckRequest{Service: "order"}, &healthpb.HealthCheckResponse{}, nil, noNetwork)
+	assert.True(t, errors.Is(err, ErrReplayUnmatched))
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+	assert.Contains(t, err.Error(), `"service":"order"`)
+	err = replay(context.Background(), "/grpc.health.v1.Health/Watch", &healthpb.HealthCheckRequest{}, &healthpb.HealthCheckResponse{}, nil, noNetwork)
+	assert.True(t, errors.Is(err, ErrReplayUnmatched))
+
+	// 只比较配置的字段
+	replay = ReplayUnaryClientInterceptor(&ReplayConf{Mode: ReplayModeReplay, Dir: dir, MatchFields: []string{"unknown"}})
+	assert.Nil(t, replay(context.Background(), method, &healthpb.HealthCheckRequest{Service: "order"}, &healthpb.HealthCheckResponse{}, nil, noNetwork))
+}
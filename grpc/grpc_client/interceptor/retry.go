@@ -0,0 +1,126 @@
+package interceptor
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/cenkalti/backoff/v4"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/fmetric"
+	"github.com/weblazy/easy/grpc/grpc_method"
+	"github.com/weblazy/easy/retry"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PreviousAttemptsHeader 重试时告知服务端之前的请求次数, 与 grpc 内置重试一致
+const PreviousAttemptsHeader = "grpc-previous-rpc-attempts"
+
+var (
+	// ClientRetryCounter 开启重试的方法每次请求的结果, attempt 从 1 开始
+	ClientRetryCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "grpc_client_retry_attempts_total",
+			Help: "Total number of RPC attempts of retryable methods on the client.",
+		}, []string{"grpc_service", "grpc_method", "grpc_code", "attempt"})
+)
+
+func init() {
+	prometheus.MustRegister(ClientRetryCounter)
+}
+
+// RetryConf 重试配置
+type RetryConf struct {
+	Backoff           retry.Config  // 重试间隔策略, 支持 constant 和 exponential
+	Methods           []string      // 允许重试的幂等方法, 完整方法名 /package.Service/Method, 支持 /package.Service/* 和 * 通配
+	Codes             []string      // 可以重试的 grpc 状态码, 如 Unavailable, DeadlineExceeded
+	PerAttemptTimeout time.Duration // 单次请求超时时间, 不会超过整体的 deadline, 0 为不限制
+}
+
+type attemptsKey struct{}
+
+// withAttempts 在 ctx 中记录请求次数, 外层拦截器可以在请求结束后读取
+func withAttempts(ctx context.Context) (context.Context, *int32) {
+	attempts := new(int32)
+	return context.WithValue(ctx, attemptsKey{}, attempts), attempts
+}
+
+func setAttempts(ctx context.Context, attempt int) {
+	if attempts, ok := ctx.Value(attemptsKey{}).(*int32); ok {
+		atomic.StoreInt32(attempts, int32(attempt))
+	}
+}
+
+// ParseCodes 解析 grpc 状态码名称, 忽略大小写, 无效的名称会被忽略
+func ParseCodes(names []string) map[codes.Code]bool {
+	result := make(map[codes.Code]bool, len(names))
+	for _, name := range names {
+		for c := codes.OK; c <= codes.Unauthenticated; c++ {
+			if strings.EqualFold(c.String(), name) {
+				result[c] = true
+			}
+		}
+	}
+	return result
+}
+
+// RetryUnaryClientInterceptor 幂等方法请求失败时按照 retry.Config 重试
+// 重试间隔超过 ctx 剩余时间时不再重试, 直接返回最后一次的错误
+func RetryUnaryClientInterceptor(config *RetryConf) grpc.UnaryClientInterceptor {
+	methods := grpc_method.NewMethodSet(config.Methods...)
+	retryCodes := ParseCodes(config.Codes)
+
+	return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+		if !methods.Has(method) {
+			return invoker(ctx, method, req, reply, cc, opts...)
+		}
+
+		service, shortMethod := fmetric.SplitGrpcMethodName(method)
+		b := config.Backoff.NewBackOffWithContext(ctx)
+		for attempt := 1; ; attempt++ {
+			err := invokeAttempt(ctx, config.PerAttemptTimeout, attempt, method, req, reply, cc, invoker, opts...)
+			setAttempts(ctx, attempt)
+			code := status.Code(err)
+			ClientRetryCounter.WithLabelValues(service, shortMethod, code.String(), strconv.Itoa(attempt)).Inc()
+			if err == nil || !retryCodes[code] || ctx.Err() != nil {
+				return err
+			}
+
+			next := b.NextBackOff()
+			if next == backoff.Stop {
+				return err
+			}
+			if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) <= next {
+				return err
+			}
+			elog.WarnCtx(ctx, "grpc client retry", elog.FieldMethod(method), zap.Int("attempt", attempt), zap.Duration("backoff", next), elog.FieldError(err))
+
+			timer := time.NewTimer(next)
+			select {
+			case <-ctx.Done():
+				timer.Stop()
+				return err
+			case <-timer.C:
+			}
+		}
+	}
+}
+
+func invokeAttempt(ctx context.Context, timeout time.Duration, attempt int, method string, req, reply interface{}, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+	if attempt > 1 {
+		ctx = metadata.AppendToOutgoingContext(ctx, PreviousAttemptsHeader, strconv.Itoa(attempt-1))
+	}
+	if timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, timeout)
+		defer cancel()
+	}
+	return invoker(ctx, method, req, reply, cc, opts...)
+}
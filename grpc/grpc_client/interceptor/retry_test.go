@@ -0,0 +1,135 @@
+package interceptor
+
+import (
+	"context"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/retry"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func newRetryConf() *RetryConf {
+	return &RetryConf{
+		Backoff: retry.Config{
+			Policy:     retry.PolicyConstant,
+			Duration:   time.Millisecond,
+			MaxRetries: 2,
+		},
+		Methods: []string{"/user.UserService/*"},
+		Codes:   []string{"unavailable", "DeadlineExceeded"},
+	}
+}
+
+func TestRetryUnaryClientInterceptor(t *testing.T) {
+	conf := newRetryConf()
+	conf.PerAttemptTimeout = 20 * time.Millisecond
+	mw := RetryUnaryClientInterceptor(conf)
+
+	// fails 为失败次数, 失败时返回 code
+	newInvoker := func(fails int32, code codes.Code) (grpc.UnaryInvoker, *int32) {
+		calls := new(int32)
+		return func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+			n := atomic.AddInt32(calls, 1)
+			md, _ := metadata.FromOutgoingContext(ctx)
+			if n > 1 {
+				assert.Equal(t, []string{strconv.Itoa(int(n - 1))}, md.Get(PreviousAttemptsHeader))
+			}
+			if n > fails {
+				return nil
+			}
+			if code == codes.DeadlineExceeded {
+				<-ctx.Done()
+			}
+			return status.Error(code, code.String())
+		}, calls
+	}
+
+	// 重试后成功, 记录请求次数
+	invoker, calls := newInvoker(2, codes.Unavailable)
+	ctx, attempts := withAttempts(context.Background())
+	assert.Nil(t, mw(ctx, "/user.UserService/GetUserInfo", nil, nil, nil, invoker))
+	assert.Equal(t, int32(3), *calls)
+	assert.Equal(t, int32(3), *attempts)
+
+	// 超过最大重试次数
+	invoker, calls = newInvoker(5, codes.Unavailable)
+	err := mw(context.Background(), "/user.UserService/GetUserInfo", nil, nil, nil, invoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(3), *calls)
+
+	// 单次请求超时后重试
+	invoker, calls = newInvoker(1, codes.DeadlineExceeded)
+	assert.Nil(t, mw(context.Background(), "/user.UserService/GetUserInfo", nil, nil, nil, invoker))
+	assert.Equal(t, int32(2), *calls)
+
+	// 不可重试的状态码
+	invoker, calls = newInvoker(1, codes.InvalidArgument)
+	err = mw(context.Background(), "/user.UserService/GetUserInfo", nil, nil, nil, invoker)
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+	assert.Equal(t, int32(1), *calls)
+
+	// 未开启重试的方法
+	invoker, calls = newInvoker(1, codes.Unavailable)
+	err = mw(context.Background(), "/order.OrderService/CreateOrder", nil, nil, nil, invoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(1), *calls)
+}
+
+func TestRetryUnaryClientInterceptor_deadline(t *testing.T) {
+	conf := newRetryConf()
+	conf.Backoff.Duration = 100 * time.Millisecond
+	mw := RetryUnaryClientInterceptor(conf)
+
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		atomic.AddInt32(&calls, 1)
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+
+	// 重试间隔超过剩余时间时直接返回
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := mw(ctx, "/user.UserService/GetUserInfo", nil, nil, nil, invoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(1), calls)
+	assert.Less(t, time.Since(start), 50*time.Millisecond)
+}
+
+func TestRetryUnaryClientInterceptor_exponential(t *testing.T) {
+	conf := newRetryConf()
+	conf.Backoff = retry.Config{
+		Policy:              retry.PolicyExponential,
+		InitialInterval:     20 * time.Millisecond,
+		RandomizationFactor: 0.2,
+		Multiplier:          2,
+		MaxInterval:         time.Second,
+		MaxRetries:          2,
+	}
+	mw := RetryUnaryClientInterceptor(conf)
+
+	var calls int32
+	invoker := func(ctx context.Context, method string, req, reply interface{}, cc *grpc.ClientConn, opts ...grpc.CallOption) error {
+		atomic.AddInt32(&calls, 1)
+		return status.Error(codes.Unavailable, "unavailable")
+	}
+
+	// 重试间隔约为 20ms 和 40ms
+	start := time.Now()
+	err := mw(context.Background(), "/user.UserService/GetUserInfo", nil, nil, nil, invoker)
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, int32(3), calls)
+	assert.GreaterOrEqual(t, time.Since(start), 48*time.Millisecond)
+	assert.Less(t, time.Since(start), 200*time.Millisecond)
+}
+
+func TestParseCodes(t *testing.T) {
+	assert.Equal(t, map[codes.Code]bool{codes.Unavailable: true, codes.Aborted: true}, ParseCodes([]string{"UNAVAILABLE", "Aborted", "unknown_code"}))
+}
@@ -0,0 +1,202 @@
+package nacos_resolver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/nacos-group/nacos-sdk-go/clients/naming_client"
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"github.com/weblazy/easy/econfig/nacos"
+	"github.com/weblazy/easy/elog"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/resolver"
+)
+
+// Scheme grpc_client 连接地址为 nacos:///appname 时使用 nacos 服务发现
+const Scheme = "nacos"
+
+// 连接地址支持的参数, 如 nacos:///user?group=BIZ&clusters=hz,sh&meta.version=v2
+const (
+	QueryGroup          = "group"    // 服务分组, 默认 DEFAULT_GROUP
+	QueryClusters       = "clusters" // 集群, 多个使用逗号分隔, 默认全部集群
+	QueryMetadataPrefix = "meta."    // 实例 metadata 过滤, 所有条件都满足的实例才会被使用
+)
+
+var (
+	ErrNamingClientNil = errors.New("nacos naming client is nil, init it by nacos.NewNacosNaming")
+	ErrServiceNameNil  = errors.New("nacos service name is empty, addr should be nacos:///appname")
+	ErrNoInstance      = errors.New("nacos service has no available instance")
+)
+
+var emptyCtx = context.Background()
+
+func init() {
+	resolver.Register(NewBuilder(nil))
+}
+
+type builder struct {
+	client naming_client.INamingClient
+}
+
+// NewBuilder 创建 nacos resolver, client 为空时使用 nacos.GetNamingClient()
+// 单测时可以配合 grpc.WithResolvers 使用 nacos.LocalNaming
+func NewBuilder(client naming_client.INamingClient) resolver.Builder {
+	return &builder{client: client}
+}
+
+func (b *builder) Scheme() string {
+	return Scheme
+}
+
+func (b *builder) Build(target resolver.Target, cc resolver.ClientConn, opts resolver.BuildOptions) (resolver.Resolver, error) {
+	client := b.client
+	if client == nil {
+		client = nacos.GetNamingClient()
+	}
+	if client == nil {
+		return nil, ErrNamingClientNil
+	}
+	serviceName := strings.TrimPrefix(target.URL.Path, "/")
+	if serviceName == "" {
+		return nil, ErrServiceNameNil
+	}
+
+	query := target.URL.Query()
+	r := &nacosResolver{
+		client: client,
+		cc:     cc,
+		filter: NewFilter(query),
+	}
+	r.param = &vo.SubscribeParam{
+		ServiceName: serviceName,
+		GroupName:   query.Get(QueryGroup),
+		Clusters:    r.filter.Clusters,
+		SubscribeCallback: func(services []model.SubscribeService, err error) {
+			r.resolve()
+		},
+	}
+	r.resolve()
+	if err := client.Subscribe(r.param); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Filter 实例过滤条件
+type Filter struct {
+	Clusters []string
+	Metadata map[string]string
+}
+
+// NewFilter 从连接地址参数中解析过滤条件
+func NewFilter(query map[string][]string) *Filter {
+	f := &Filter{Metadata: make(map[string]string)}
+	for k, v := range query {
+		if len(v) == 0 {
+			continue
+		}
+		switch {
+		case k == QueryClusters:
+			for _, cluster := range strings.Split(v[0], ",") {
+				if cluster = strings.TrimSpace(cluster); cluster != "" {
+					f.Clusters = append(f.Clusters, cluster)
+				}
+			}
+		case strings.HasPrefix(k, QueryMetadataPrefix):
+			f.Metadata[strings.TrimPrefix(k, QueryMetadataPrefix)] = v[0]
+		}
+	}
+	return f
+}
+
+// Match 实例健康, 启用, 权重大于 0 且满足集群和 metadata 条件
+// 权重只用于摘除实例, round_robin 和 consistent_hash 不按权重分配流量
+func (f *Filter) Match(instance model.Instance) bool {
+	if !instance.Healthy || !instance.Enable || instance.Weight <= 0 {
+		return false
+	}
+	if len(f.Clusters) > 0 {
+		match := false
+		for _, cluster := range f.Clusters {
+			if cluster == instance.ClusterName {
+				match = true
+				break
+			}
+		}
+		if !match {
+			return false
+		}
+	}
+	for k, v := range f.Metadata {
+		if instance.Metadata[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+type nacosResolver struct {
+	client naming_client.INamingClient
+	cc     resolver.ClientConn
+	filter *Filter
+	param  *vo.SubscribeParam
+	mu     sync.Mutex
+	addrs  []resolver.Address
+}
+
+// resolve 查询实例并更新负载均衡地址
+// nacos 推送空列表时(如 nacos 服务异常)保留上一次的地址, 避免所有请求失败
+func (r *nacosResolver) resolve() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	instances, err := r.client.SelectAllInstances(vo.SelectAllInstancesParam{
+		ServiceName: r.param.ServiceName,
+		GroupName:   r.param.GroupName,
+		Clusters:    r.param.Clusters,
+	})
+	if err != nil {
+		elog.ErrorCtx(emptyCtx, "nacos resolver select instances err", zap.String("service", r.param.ServiceName), elog.FieldError(err))
+		r.cc.ReportError(err)
+		return
+	}
+
+	addrs := make([]resolver.Address, 0, len(instances))
+	for _, instance := range instances {
+		if !r.filter.Match(instance) {
+			continue
+		}
+		addrs = append(addrs, resolver.Address{Addr: net.JoinHostPort(instance.Ip, strconv.FormatUint(instance.Port, 10))})
+	}
+	if len(addrs) == 0 {
+		if len(r.addrs) > 0 {
+			elog.WarnCtx(emptyCtx, "nacos resolver no available instance, keep last addresses", zap.String("service", r.param.ServiceName), zap.Int("addrs", len(r.addrs)))
+			return
+		}
+		r.cc.ReportError(fmt.Errorf("%w: %s", ErrNoInstance, r.param.ServiceName))
+		return
+	}
+
+	r.addrs = addrs
+	elog.InfoCtx(emptyCtx, "nacos resolver update addresses", zap.String("service", r.param.ServiceName), zap.Int("addrs", len(addrs)))
+	if err := r.cc.UpdateState(resolver.State{Addresses: addrs}); err != nil {
+		elog.WarnCtx(emptyCtx, "nacos resolver update state err", zap.String("service", r.param.ServiceName), elog.FieldError(err))
+	}
+}
+
+// ResolveNow 可能在 UpdateState 过程中被调用, 异步执行避免死锁
+func (r *nacosResolver) ResolveNow(resolver.ResolveNowOptions) {
+	go r.resolve()
+}
+
+func (r *nacosResolver) Close() {
+	if err := r.client.Unsubscribe(r.param); err != nil {
+		elog.WarnCtx(emptyCtx, "nacos resolver unsubscribe err", zap.String("service", r.param.ServiceName), elog.FieldError(err))
+	}
+}
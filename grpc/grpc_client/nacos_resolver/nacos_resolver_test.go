@@ -0,0 +1,150 @@
+package nacos_resolver
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/econfig/nacos"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/resolver"
+	"google.golang.org/grpc/serviceconfig"
+)
+
+type mockClientConn struct {
+	mu    sync.Mutex
+	state resolver.State
+	err   error
+}
+
+func (m *mockClientConn) UpdateState(state resolver.State) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.state = state
+	return nil
+}
+
+func (m *mockClientConn) ReportError(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
+
+func (m *mockClientConn) NewAddress(addresses []resolver.Address) {}
+
+func (m *mockClientConn) NewServiceConfig(serviceConfig string) {}
+
+func (m *mockClientConn) ParseServiceConfig(serviceConfigJSON string) *serviceconfig.ParseResult {
+	return nil
+}
+
+func (m *mockClientConn) addrs() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	addrs := make([]string, 0, len(m.state.Addresses))
+	for _, addr := range m.state.Addresses {
+		addrs = append(addrs, addr.Addr)
+	}
+	return addrs
+}
+
+func newTarget(t *testing.T, addr string) resolver.Target {
+	u, err := url.Parse(addr)
+	assert.Nil(t, err)
+	return resolver.Target{URL: *u}
+}
+
+func TestNacosResolver(t *testing.T) {
+	naming := nacos.NewLocalNaming()
+	naming.SetInstances("user", "",
+		model.Instance{Ip: "10.0.0.1", Port: 9090, Weight: 1, Enable: true, Healthy: true, ClusterName: "hz", Metadata: map[string]string{"version": "v2"}},
+		model.Instance{Ip: "10.0.0.2", Port: 9090, Weight: 2, Enable: true, Healthy: true, ClusterName: "sh", Metadata: map[string]string{"version": "v2"}},
+		model.Instance{Ip: "10.0.0.3", Port: 9090, Weight: 1, Enable: true, Healthy: false, ClusterName: "hz", Metadata: map[string]string{"version": "v2"}},
+		model.Instance{Ip: "10.0.0.4", Port: 9090, Weight: 1, Enable: false, Healthy: true, ClusterName: "hz", Metadata: map[string]string{"version": "v2"}},
+		model.Instance{Ip: "10.0.0.5", Port: 9090, Weight: 0, Enable: true, Healthy: true, ClusterName: "hz", Metadata: map[string]string{"version": "v2"}},
+		model.Instance{Ip: "10.0.0.6", Port: 9090, Weight: 1, Enable: true, Healthy: true, ClusterName: "hz", Metadata: map[string]string{"version": "v1"}},
+	)
+
+	cc := &mockClientConn{}
+	r, err := NewBuilder(naming).Build(newTarget(t, "nacos:///user?meta.version=v2"), cc, resolver.BuildOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.1:9090", "10.0.0.2:9090"}, cc.addrs())
+
+	// 实例变更后推送新地址
+	naming.SetInstances("user", "",
+		model.Instance{Ip: "10.0.0.7", Port: 9090, Weight: 1, Enable: true, Healthy: true, Metadata: map[string]string{"version": "v2"}},
+	)
+	assert.Equal(t, []string{"10.0.0.7:9090"}, cc.addrs())
+
+	// 推送空列表时保留上一次的地址
+	naming.SetInstances("user", "")
+	assert.Equal(t, []string{"10.0.0.7:9090"}, cc.addrs())
+
+	// 关闭后不再接收推送
+	r.Close()
+	naming.SetInstances("user", "",
+		model.Instance{Ip: "10.0.0.8", Port: 9090, Weight: 1, Enable: true, Healthy: true, Metadata: map[string]string{"version": "v2"}},
+	)
+	assert.Equal(t, []string{"10.0.0.7:9090"}, cc.addrs())
+
+	// 集群过滤
+	cc = &mockClientConn{}
+	naming.SetInstances("user", "BIZ",
+		model.Instance{Ip: "10.0.0.1", Port: 9090, Weight: 1, Enable: true, Healthy: true, ClusterName: "hz"},
+		model.Instance{Ip: "10.0.0.2", Port: 9090, Weight: 1, Enable: true, Healthy: true, ClusterName: "sh"},
+	)
+	_, err = NewBuilder(naming).Build(newTarget(t, "nacos:///user?group=BIZ&clusters=sh"), cc, resolver.BuildOptions{})
+	assert.Nil(t, err)
+	assert.Equal(t, []string{"10.0.0.2:9090"}, cc.addrs())
+
+	// 没有实例时报告错误
+	cc = &mockClientConn{}
+	_, err = NewBuilder(naming).Build(newTarget(t, "nacos:///order"), cc, resolver.BuildOptions{})
+	assert.Nil(t, err)
+	assert.ErrorIs(t, cc.err, ErrNoInstance)
+
+	_, err = NewBuilder(naming).Build(newTarget(t, "nacos:///"), cc, resolver.BuildOptions{})
+	assert.Equal(t, ErrServiceNameNil, err)
+}
+
+func TestNacosResolver_dial(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	server := grpc.NewServer()
+	healthpb.RegisterHealthServer(server, health.NewServer())
+	go server.Serve(listener)
+	defer server.Stop()
+
+	naming := nacos.NewLocalNaming()
+	tcpAddr := listener.Addr().(*net.TCPAddr)
+	_, err = naming.RegisterInstance(vo.RegisterInstanceParam{
+		Ip:          tcpAddr.IP.String(),
+		Port:        uint64(tcpAddr.Port),
+		Weight:      1,
+		Enable:      true,
+		Healthy:     true,
+		ServiceName: "user",
+	})
+	assert.Nil(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 3*time.Second)
+	defer cancel()
+	cc, err := grpc.DialContext(ctx, "nacos:///user", grpc.WithBlock(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithResolvers(NewBuilder(naming)),
+	)
+	assert.Nil(t, err)
+	defer cc.Close()
+	resp, err := healthpb.NewHealthClient(cc).Check(ctx, &healthpb.HealthCheckRequest{})
+	assert.Nil(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, resp.Status)
+}
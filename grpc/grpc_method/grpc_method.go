@@ -0,0 +1,42 @@
+package grpc_method
+
+import "strings"
+
+const methodWildcard = "*"
+
+// Keys 返回方法名匹配的候选 key, 按优先级排序: 完整方法名 > /package.Service/* > *
+// viper 解析配置时 map key 会被转为小写, 所以方法名统一按小写匹配
+func Keys(fullMethod string) []string {
+	fullMethod = strings.ToLower(fullMethod)
+	keys := make([]string, 0, 3)
+	keys = append(keys, fullMethod)
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		keys = append(keys, fullMethod[:i+1]+methodWildcard)
+	}
+	return append(keys, methodWildcard)
+}
+
+// MethodSet 方法名集合, 支持完整方法名 /package.Service/Method, /package.Service/* 和 * 通配
+type MethodSet map[string]struct{}
+
+// NewMethodSet 创建方法名集合
+func NewMethodSet(methods ...string) MethodSet {
+	s := make(MethodSet, len(methods))
+	for _, m := range methods {
+		s[strings.ToLower(m)] = struct{}{}
+	}
+	return s
+}
+
+// Has 方法是否在集合中
+func (s MethodSet) Has(fullMethod string) bool {
+	if len(s) == 0 {
+		return false
+	}
+	for _, key := range Keys(fullMethod) {
+		if _, ok := s[key]; ok {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,16 @@
+package grpc_method
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMethodSet(t *testing.T) {
+	s := NewMethodSet("/user.UserService/Login", "/grpc.health.v1.Health/*")
+	assert.True(t, s.Has("/user.UserService/Login"))
+	assert.True(t, s.Has("/grpc.health.v1.Health/Check"))
+	assert.False(t, s.Has("/user.UserService/GetUserInfo"))
+	assert.True(t, NewMethodSet("*").Has("/a.A/B"))
+	assert.False(t, NewMethodSet().Has("/a.A/B"))
+}
@@ -3,9 +3,15 @@ package grpc_server
 import (
 	"context"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/weblazy/easy/econfig/nacos"
 	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/etls"
 	"github.com/weblazy/easy/etrace"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/peer"
 	"google.golang.org/grpc/reflection"
 
@@ -33,6 +39,15 @@ type GrpcServer struct {
 	*grpc.Server
 	listener net.Listener
 	quit     chan struct{}
+	quitOnce sync.Once
+	err      error
+
+	health         *health.Server
+	healthCheckers []healthChecker
+	stopHealth     chan struct{}
+	stopHealthOnce sync.Once
+
+	registrar *nacos.Registrar
 }
 
 func NewGrpcServer(config *grpc_server_config.Config) *GrpcServer {
@@ -41,6 +56,17 @@ func NewGrpcServer(config *grpc_server_config.Config) *GrpcServer {
 	}
 	BuildServerOptions(config)
 
+	var err error
+	if tlsConfig := config.TLSConfig(); tlsConfig.Enable() {
+		var creds credentials.TransportCredentials
+		creds, err = etls.NewServerCredentials(tlsConfig)
+		if err != nil {
+			elog.ErrorCtx(emptyCtx, "load grpc server certificate err", elog.FieldError(err))
+		} else {
+			config.ServerOptions = append(config.ServerOptions, grpc.Creds(creds))
+		}
+	}
+
 	newServer := grpc.NewServer(config.ServerOptions...)
 
 	if config.EnableServerReflection {
@@ -48,16 +74,21 @@ func NewGrpcServer(config *grpc_server_config.Config) *GrpcServer {
 		reflection.Register(newServer)
 	}
 
+	var healthServer *health.Server
 	if config.EnableHealth {
 		elog.InfoCtx(emptyCtx, "enable grpc health")
-		healthpb.RegisterHealthServer(newServer, health.NewServer())
+		healthServer = health.NewServer()
+		healthpb.RegisterHealthServer(newServer, healthServer)
 	}
 
 	return &GrpcServer{
-		config:   config,
-		Server:   newServer,
-		listener: nil,
-		quit:     make(chan struct{}),
+		config:     config,
+		Server:     newServer,
+		listener:   nil,
+		quit:       make(chan struct{}),
+		err:        err,
+		health:     healthServer,
+		stopHealth: make(chan struct{}),
 	}
 }
 
@@ -73,6 +104,10 @@ func (c *GrpcServer) PackageName() string {
 
 // Init 初始化
 func (c *GrpcServer) Init() error {
+	// 证书加载失败不能以明文启动
+	if c.err != nil {
+		return c.err
+	}
 	var (
 		listener net.Listener
 		err      error
@@ -91,11 +126,17 @@ func (c *GrpcServer) Init() error {
 	c.config.Port = listener.Addr().(*net.TCPAddr).Port
 
 	c.listener = listener
+	c.initRegistrar()
 	return nil
 }
 
 // Start implements server.Component interface.
+// 开启服务注册时, 注册失败不启动服务
 func (c *GrpcServer) Start() error {
+	if err := c.register(); err != nil {
+		return err
+	}
+	c.startHealthCheck()
 	err := c.Server.Serve(c.listener)
 	return err
 }
@@ -103,21 +144,40 @@ func (c *GrpcServer) Start() error {
 // Stop implements server.Component interface
 // it will terminate echo server immediately
 func (c *GrpcServer) Stop() error {
+	c.deregister()
+	c.shutdownHealth()
 	c.Server.Stop()
 	return nil
 }
 
 // GracefulStop implements server.Component interface
 // it will stop echo server gracefully
+// 先从 nacos 注销, health 置为 NOT_SERVING, 等待 DrainDelay 让上游摘除流量后再关闭
+// DrainDelay 最多等待到 ctx 结束, 之后总会关闭 listener, 超时时强制关闭连接
 func (c *GrpcServer) GracefulStop(ctx context.Context) error {
-	go func() {
-		c.Server.GracefulStop()
-		close(c.quit)
-	}()
+	c.deregister()
+	c.shutdownHealth()
+	if (c.health != nil || c.registrar != nil) && c.config.DrainDelay > 0 {
+		elog.InfoCtx(ctx, "grpc health not serving, waiting for drain", zap.Duration("drain_delay", c.config.DrainDelay))
+		select {
+		case <-ctx.Done():
+			elog.WarnCtx(ctx, "grpc drain timeout")
+		case <-time.After(c.config.DrainDelay):
+		}
+	}
+
+	// 可以重复调用, 只关闭一次 quit
+	c.quitOnce.Do(func() {
+		go func() {
+			c.Server.GracefulStop()
+			close(c.quit)
+		}()
+	})
 
 	select {
 	case <-ctx.Done():
 		elog.WarnCtx(ctx, "grpc graceful shutdown timeout")
+		c.Server.Stop()
 		return ctx.Err()
 	case <-c.quit:
 		elog.InfoCtx(ctx, "grpc graceful shutdown success")
@@ -140,6 +200,18 @@ func (c *GrpcServer) IsBufNet() bool {
 	return c.config.Network == networkTypeBufNet
 }
 
+// Dialer 返回连接当前 server 的 dialer, 可以通过 grpc.WithContextDialer 使用
+// bufnet 时直接连接内存 listener, 需要在 Init 之后调用
+func (c *GrpcServer) Dialer() func(ctx context.Context, addr string) (net.Conn, error) {
+	return func(ctx context.Context, addr string) (net.Conn, error) {
+		if listener, ok := c.listener.(*bufconn.Listener); ok {
+			return listener.DialContext(ctx)
+		}
+		var d net.Dialer
+		return d.DialContext(ctx, c.config.Network, c.Address())
+	}
+}
+
 // getPeerIP 获取对端ip
 func getPeerIP(ctx context.Context) string {
 	// 从grpc里取对端ip
@@ -158,22 +230,40 @@ func getPeerIP(ctx context.Context) string {
 }
 
 func BuildServerOptions(config *grpc_server_config.Config) {
-	// 暂时没有 stream 需求
 	var streamInterceptors []grpc.StreamServerInterceptor
 	var unaryInterceptors []grpc.UnaryServerInterceptor
 
 	// trace 必须在最外层，否则无法取到trace信息，传递到其他中间件
 	if config.EnableTraceInterceptor {
 		unaryInterceptors = append(unaryInterceptors, etrace.UnaryServerInterceptor())
+		streamInterceptors = append(streamInterceptors, etrace.StreamServerInterceptor())
 	}
 	unaryInterceptors = append(unaryInterceptors, interceptor.GrpcHeaderCarrierInterceptor())
 	unaryInterceptors = append(unaryInterceptors, config.PrependUnaryInterceptors...)
 	unaryInterceptors = append(unaryInterceptors, interceptor.GrpcLogger(config))
 
+	streamInterceptors = append(streamInterceptors, interceptor.GrpcStreamHeaderCarrierInterceptor())
+	streamInterceptors = append(streamInterceptors, config.PrependStreamInterceptors...)
+	streamInterceptors = append(streamInterceptors, interceptor.GrpcStreamLogger(config))
+
 	if config.EnableMetricInterceptor {
 		unaryInterceptors = append(unaryInterceptors, interceptor.MetricUnaryServerInterceptor(config.MetricSuccessCodes))
+		streamInterceptors = append(streamInterceptors, interceptor.MetricStreamServerInterceptor(config.MetricSuccessCodes))
 	}
 
+	// recovery 放在日志和监控之后, panic 转换成 error 后才能被记录
+	streamInterceptors = append(streamInterceptors, interceptor.StreamRecoveryInterceptor())
+
+	// 超时控制放在监控之后, 超时会以 DeadlineExceeded 记录到监控
+	if config.MinDeadlineDuration > 0 || config.MaxDeadlineDuration > 0 || len(config.MethodTimeouts) > 0 {
+		unaryInterceptors = append(unaryInterceptors, interceptor.UnaryDeadlineInterceptor(config))
+		streamInterceptors = append(streamInterceptors, interceptor.StreamDeadlineInterceptor(config))
+	}
+
+	// 业务错误转换为带 detail 的 status, 调用方可以通过 code_err.GetCodeErr 解析
+	unaryInterceptors = append(unaryInterceptors, interceptor.UnaryCodeErrInterceptor())
+	streamInterceptors = append(streamInterceptors, interceptor.StreamCodeErrInterceptor())
+
 	streamInterceptors = append(
 		streamInterceptors,
 		config.StreamInterceptors...,
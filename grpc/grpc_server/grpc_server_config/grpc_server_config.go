@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/weblazy/easy/econfig/nacos"
 	"github.com/weblazy/easy/elog"
 	"github.com/weblazy/easy/elog/ezap"
+	"github.com/weblazy/easy/etls"
 	"google.golang.org/grpc"
 )
 
@@ -17,30 +19,44 @@ const (
 // Config ...
 type Config struct {
 	Name                       string
-	Host                       string        // IP地址，默认0.0.0.0
-	Port                       int           // Port端口，默认9090
-	Network                    string        // 网络类型，默认tcp4
-	EnableMetricInterceptor    bool          // 是否开启监控，默认开启
-	EnableTraceInterceptor     bool          // 是否开启链路追踪，默认开启
-	EnableSkipHealthLog        bool          // 是否屏蔽探活日志，默认关闭
-	SlowLogThreshold           time.Duration // 服务慢日志，默认500ms
-	EnableAccessInterceptor    bool          // 是否开启，记录请求数据
-	EnableAccessInterceptorReq bool          // 是否开启记录请求参数，默认不开启
-	EnableAccessInterceptorRes bool          // 是否开启记录响应参数，默认不开启
-	EnableServerReflection     bool          // 是否开启 reflection, 默认开启
-	EnableHealth               bool          // 是否开启 grpc health, 默认开启
-	MinDeadlineDuration        time.Duration // server handler ctx 最短超时时间, 默认 10s
-	MetricSuccessCodes         []string      // metric 监控, 统一将此列表中的 biz code rewrite 成统一成功 code 20000, 默认为空不做操作
+	Host                       string                   // IP地址，默认0.0.0.0
+	Port                       int                      // Port端口，默认9090
+	Network                    string                   // 网络类型，默认tcp4
+	EnableMetricInterceptor    bool                     // 是否开启监控，默认开启
+	EnableTraceInterceptor     bool                     // 是否开启链路追踪，默认开启
+	EnableSkipHealthLog        bool                     // 是否屏蔽探活日志，默认关闭
+	SlowLogThreshold           time.Duration            // 服务慢日志，默认500ms
+	EnableAccessInterceptor    bool                     // 是否开启，记录请求数据
+	EnableAccessInterceptorReq bool                     // 是否开启记录请求参数，默认不开启
+	EnableAccessInterceptorRes bool                     // 是否开启记录响应参数，默认不开启
+	EnableServerReflection     bool                     // 是否开启 reflection, 默认开启
+	EnableHealth               bool                     // 是否开启 grpc health, 默认开启
+	HealthCheckInterval        time.Duration            // 依赖检查间隔, 默认 10s
+	HealthCheckTimeout         time.Duration            // 单次依赖检查超时时间, 默认 3s
+	DrainDelay                 time.Duration            // 优雅关闭时 health 置为 NOT_SERVING 后等待流量摘除的时间, 建议大于 k8s readinessProbe 的 periodSeconds, 默认 0
+	MinDeadlineDuration        time.Duration            // server handler ctx 最短超时时间, 客户端 deadline 小于该值时按该值处理, 默认 0 不处理
+	MaxDeadlineDuration        time.Duration            // server handler ctx 最长超时时间, 客户端 deadline 大于该值或未设置时按该值处理, 默认 0 不限制
+	MethodTimeouts             map[string]time.Duration // 方法级超时时间, key 为完整方法名 /package.Service/Method, 支持 /package.Service/* 和 * 通配, 默认为空
+	MetricSuccessCodes         []string                 // metric 监控, 统一将此列表中的 biz code rewrite 成统一成功 code 20000, 默认为空不做操作
 	// Deprecated: not affect anything
 	EnableSkyWalking bool // 是否额外开启 skywalking, 默认开启
 
-	ServerOptions            []grpc.ServerOption
-	StreamInterceptors       []grpc.StreamServerInterceptor
-	UnaryInterceptors        []grpc.UnaryServerInterceptor
-	PrependUnaryInterceptors []grpc.UnaryServerInterceptor
+	ServerOptions             []grpc.ServerOption
+	StreamInterceptors        []grpc.StreamServerInterceptor
+	UnaryInterceptors         []grpc.UnaryServerInterceptor
+	PrependUnaryInterceptors  []grpc.UnaryServerInterceptor
+	PrependStreamInterceptors []grpc.StreamServerInterceptor
 
 	EnableFielLogger bool // 将日志输出到文件
 	FielLoggerPath   string
+
+	CertFile          string        // TLS 证书文件, 配置后开启 TLS, 默认为空
+	KeyFile           string        // TLS 私钥文件
+	CaFile            string        // CA 证书文件, 需要同时配置 CertFile, KeyFile, 配置后开启 mTLS 校验客户端证书, 默认为空
+	TLSReloadInterval time.Duration // 证书文件变更检查间隔, 证书轮换后自动重新加载, 默认 1m
+
+	EnableRegistry bool                  // 是否注册到 nacos 服务发现, 默认关闭
+	Registry       nacos.RegistrarConfig // 服务注册配置, 服务名默认为 Name, 端口默认为监听端口
 }
 
 // DefaultConfig represents default config
@@ -59,11 +75,23 @@ func DefaultConfig() *Config {
 		EnableAccessInterceptorRes: true,
 		EnableServerReflection:     true,
 		EnableHealth:               true,
-		MinDeadlineDuration:        time.Second * 10,
+		HealthCheckInterval:        time.Second * 10,
+		HealthCheckTimeout:         time.Second * 3,
 		ServerOptions:              []grpc.ServerOption{},
 		StreamInterceptors:         []grpc.StreamServerInterceptor{},
 		UnaryInterceptors:          []grpc.UnaryServerInterceptor{},
 		FielLoggerPath:             PkgName,
+		TLSReloadInterval:          etls.DefaultReloadInterval,
+	}
+}
+
+// TLSConfig 证书配置
+func (config Config) TLSConfig() etls.Config {
+	return etls.Config{
+		CertFile:       config.CertFile,
+		KeyFile:        config.KeyFile,
+		CaFile:         config.CaFile,
+		ReloadInterval: config.TLSReloadInterval,
 	}
 }
 
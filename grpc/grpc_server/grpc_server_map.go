@@ -0,0 +1,45 @@
+package grpc_server
+
+import (
+	"sync"
+
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
+)
+
+var (
+	GrpcServerMap sync.Map
+	grpcServerMu  sync.Mutex
+)
+
+// GetGrpcServer 按照配置 key 获取 grpc 服务, 不存在时从 econfig.GlobalViper 读取配置创建
+// 证书加载失败时不缓存, Init 返回错误, 程序退出时按 closes.ServerPriority 优雅关闭
+func GetGrpcServer(name string) *GrpcServer {
+	if v, ok := GrpcServerMap.Load(name); ok {
+		return v.(*GrpcServer)
+	}
+	grpcServerMu.Lock()
+	defer grpcServerMu.Unlock()
+	if v, ok := GrpcServerMap.Load(name); ok {
+		return v.(*GrpcServer)
+	}
+
+	conf := grpc_server_config.DefaultConfig()
+	econfig.GlobalViper.UnmarshalKey(name, conf)
+	if conf.Name == "" {
+		conf.Name = name
+	}
+	server := NewGrpcServer(conf)
+	if server.err != nil {
+		return server
+	}
+
+	GrpcServerMap.Store(name, server)
+	ecomponent.Register(ecomponent.KindGrpcServer, name, closes.ServerPriority, func() {
+		GrpcServerMap.Delete(name)
+		_ = server.GracefulStop(emptyCtx)
+	})
+	return server
+}
@@ -3,8 +3,14 @@ package grpc_server
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/econfig/eviper"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
 	"github.com/weblazy/easy/grpc/proto/user"
 )
 
@@ -20,6 +26,41 @@ func TestNewGrpcServer(t *testing.T) {
 	})
 }
 
+func TestGetGrpcServer(t *testing.T) {
+	econfig.GlobalViper = eviper.NewViperFromString(`
+[userServer]
+Port = 9091
+`)
+	server := GetGrpcServer("userServer")
+	assert.Equal(t, "userServer", server.Name())
+	assert.Equal(t, "0.0.0.0:9091", server.Address())
+	assert.Equal(t, server, GetGrpcServer("userServer"))
+
+	var found bool
+	for _, c := range ecomponent.List() {
+		if c.Kind == ecomponent.KindGrpcServer && c.Name == "userServer" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func TestGrpcServer_GracefulStopTwice(t *testing.T) {
+	cfg := grpc_server_config.DefaultConfig()
+	cfg.Network = networkTypeBufNet
+	cfg.DrainDelay = 0
+	server := NewGrpcServer(cfg)
+	assert.Nil(t, server.Init())
+	go server.Start()
+
+	// 应用自己关闭后 closes 钩子再次关闭不会 panic
+	assert.Nil(t, server.GracefulStop(context.Background()))
+	assert.Nil(t, server.Stop())
+	assert.Nil(t, server.GracefulStop(context.Background()))
+	// 关闭在 goroutine 中执行, 等待其结束
+	time.Sleep(50 * time.Millisecond)
+}
+
 type User struct {
 	user.UserServiceServer
 }
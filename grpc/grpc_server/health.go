@@ -0,0 +1,89 @@
+package grpc_server
+
+import (
+	"context"
+	"time"
+
+	"github.com/weblazy/easy/elog"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// HealthChecker 依赖检查, 如 mysql ping, redis ping, 返回 error 时对应服务置为 NOT_SERVING
+type HealthChecker func(ctx context.Context) error
+
+type healthChecker struct {
+	service string
+	name    string
+	check   HealthChecker
+}
+
+// RegisterHealthChecker 注册依赖检查, 需要在 Start 之前调用
+// service 为 grpc 服务全名, 如 user.UserService, 为空表示整个 server
+// 同一个 service 的所有检查都通过时为 SERVING, 否则为 NOT_SERVING
+func (c *GrpcServer) RegisterHealthChecker(service string, name string, check HealthChecker) {
+	c.healthCheckers = append(c.healthCheckers, healthChecker{
+		service: service,
+		name:    name,
+		check:   check,
+	})
+}
+
+// HealthServer 返回 grpc health server, 未开启 EnableHealth 时为 nil
+func (c *GrpcServer) HealthServer() *health.Server {
+	return c.health
+}
+
+// startHealthCheck 立即执行一次依赖检查, 之后按 HealthCheckInterval 定时检查
+func (c *GrpcServer) startHealthCheck() {
+	if c.health == nil || len(c.healthCheckers) == 0 {
+		return
+	}
+	c.checkHealth()
+	if c.config.HealthCheckInterval <= 0 {
+		return
+	}
+	go func() {
+		ticker := time.NewTicker(c.config.HealthCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-c.stopHealth:
+				return
+			case <-ticker.C:
+				c.checkHealth()
+			}
+		}
+	}()
+}
+
+func (c *GrpcServer) checkHealth() {
+	result := make(map[string]healthpb.HealthCheckResponse_ServingStatus)
+	for _, checker := range c.healthCheckers {
+		if _, ok := result[checker.service]; !ok {
+			result[checker.service] = healthpb.HealthCheckResponse_SERVING
+		}
+		ctx, cancel := context.WithTimeout(emptyCtx, c.config.HealthCheckTimeout)
+		err := checker.check(ctx)
+		cancel()
+		if err != nil {
+			result[checker.service] = healthpb.HealthCheckResponse_NOT_SERVING
+			elog.WarnCtx(emptyCtx, "grpc health check failed", zap.String("service", checker.service), elog.FieldName(checker.name), elog.FieldError(err))
+		}
+	}
+	// Shutdown 之后的 SetServingStatus 会被忽略, 不会覆盖 NOT_SERVING
+	for service, status := range result {
+		c.health.SetServingStatus(service, status)
+	}
+}
+
+// shutdownHealth 停止依赖检查并将所有服务置为 NOT_SERVING
+func (c *GrpcServer) shutdownHealth() {
+	c.stopHealthOnce.Do(func() {
+		close(c.stopHealth)
+	})
+	if c.health != nil {
+		c.health.Shutdown()
+	}
+}
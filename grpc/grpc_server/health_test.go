@@ -0,0 +1,93 @@
+package grpc_server
+
+import (
+	"context"
+	"errors"
+	"net"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+func TestGrpcServer_Health(t *testing.T) {
+	cfg := grpc_server_config.DefaultConfig()
+	cfg.Network = networkTypeBufNet
+	cfg.HealthCheckInterval = 10 * time.Millisecond
+	cfg.DrainDelay = 50 * time.Millisecond
+	server := NewGrpcServer(cfg)
+
+	var redisDown atomic.Bool
+	redisDown.Store(true)
+	server.RegisterHealthChecker("", "mysql", func(ctx context.Context) error { return nil })
+	server.RegisterHealthChecker("user.UserService", "redis", func(ctx context.Context) error {
+		if redisDown.Load() {
+			return errors.New("redis down")
+		}
+		return nil
+	})
+
+	assert.Nil(t, server.Init())
+	go server.Start()
+
+	cc, err := grpc.Dial("bufnet", grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithContextDialer(func(ctx context.Context, s string) (net.Conn, error) {
+			return server.Listener().(*bufconn.Listener).Dial()
+		}))
+	assert.Nil(t, err)
+	defer cc.Close()
+	client := healthpb.NewHealthClient(cc)
+	check := func(service string) healthpb.HealthCheckResponse_ServingStatus {
+		resp, err := client.Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+		assert.Nil(t, err)
+		return resp.GetStatus()
+	}
+
+	assert.Eventually(t, func() bool {
+		return check("user.UserService") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 10*time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, check(""))
+
+	redisDown.Store(false)
+	assert.Eventually(t, func() bool {
+		return check("user.UserService") == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+
+	// 优雅关闭开始后 health 立即变为 NOT_SERVING, 等待 DrainDelay 后才关闭
+	done := make(chan error)
+	go func() {
+		done <- server.GracefulStop(context.Background())
+	}()
+	assert.Eventually(t, func() bool {
+		return check("") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, 5*time.Millisecond)
+	assert.Nil(t, <-done)
+}
+
+func TestGrpcServer_GracefulStopDrainTimeout(t *testing.T) {
+	cfg := grpc_server_config.DefaultConfig()
+	cfg.Network = networkTypeBufNet
+	cfg.DrainDelay = time.Minute
+	server := NewGrpcServer(cfg)
+	assert.Nil(t, server.Init())
+	done := make(chan error)
+	go func() {
+		done <- server.Start()
+	}()
+
+	// DrainDelay 超过 ctx 时仍然关闭 server
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, server.GracefulStop(ctx))
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("server not stopped")
+	}
+}
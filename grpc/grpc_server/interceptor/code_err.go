@@ -0,0 +1,38 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/weblazy/easy/code_err"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+)
+
+// UnaryCodeErrInterceptor 将 handler 返回的被 wrap 的 *code_err.CodeErr 转换为带 detail 的 grpc status
+// 未被 wrap 的 *code_err.CodeErr 实现了 GRPCStatus, grpc 会自动转换
+func UnaryCodeErrInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		return resp, toStatusErr(err)
+	}
+}
+
+// StreamCodeErrInterceptor 将 handler 返回的被 wrap 的 *code_err.CodeErr 转换为带 detail 的 grpc status
+func StreamCodeErrInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return toStatusErr(handler(srv, ss))
+	}
+}
+
+func toStatusErr(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := err.(interface{ GRPCStatus() *status.Status }); ok {
+		return err
+	}
+	if codeErr, ok := code_err.FromError(err); ok {
+		return codeErr.GRPCStatus().Err()
+	}
+	return err
+}
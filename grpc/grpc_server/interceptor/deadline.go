@@ -0,0 +1,121 @@
+package interceptor
+
+import (
+	"context"
+	"strings"
+	"time"
+
+	"github.com/weblazy/easy/ectx"
+	"github.com/weblazy/easy/grpc/grpc_method"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
+	"google.golang.org/grpc"
+)
+
+// HandlerDeadline 计算 server handler 的超时时间
+type HandlerDeadline struct {
+	minDeadline    time.Duration
+	maxDeadline    time.Duration
+	methodTimeouts map[string]time.Duration
+}
+
+// NewHandlerDeadline 根据配置创建 HandlerDeadline
+// viper 解析配置时 map key 会被转为小写, 所以方法名统一按小写匹配
+func NewHandlerDeadline(config *grpc_server_config.Config) *HandlerDeadline {
+	methodTimeouts := make(map[string]time.Duration, len(config.MethodTimeouts))
+	for k, v := range config.MethodTimeouts {
+		methodTimeouts[strings.ToLower(k)] = v
+	}
+	return &HandlerDeadline{
+		minDeadline:    config.MinDeadlineDuration,
+		maxDeadline:    config.MaxDeadlineDuration,
+		methodTimeouts: methodTimeouts,
+	}
+}
+
+// MethodTimeout 获取方法级超时时间, 匹配顺序: 完整方法名 > /package.Service/* > *
+func (d *HandlerDeadline) MethodTimeout(fullMethod string) (time.Duration, bool) {
+	for _, key := range grpc_method.Keys(fullMethod) {
+		if v, ok := d.methodTimeouts[key]; ok {
+			return v, true
+		}
+	}
+	return 0, false
+}
+
+// Timeout 计算 handler 超时时间
+// 1. 客户端 deadline 被限制在 [MinDeadlineDuration, MaxDeadlineDuration] 之间
+// 2. 配置了方法级超时时取两者较小值
+// detach 为 true 表示客户端 deadline 小于最短超时时间, handler ctx 需要脱离客户端 deadline
+func (d *HandlerDeadline) Timeout(ctx context.Context, fullMethod string) (timeout time.Duration, detach bool) {
+	if deadline, ok := ctx.Deadline(); ok {
+		timeout = time.Until(deadline)
+		if d.minDeadline > 0 && timeout < d.minDeadline {
+			timeout = d.minDeadline
+			detach = true
+		}
+	}
+	if d.maxDeadline > 0 && (timeout <= 0 || timeout > d.maxDeadline) {
+		timeout = d.maxDeadline
+	}
+	if v, ok := d.MethodTimeout(fullMethod); ok && v > 0 && (timeout <= 0 || v < timeout) {
+		timeout = v
+	}
+	if detach {
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) >= timeout {
+			detach = false
+		}
+	}
+	return timeout, detach
+}
+
+// WithContext 返回设置好超时时间的 handler ctx
+// 脱离客户端 deadline 时, 客户端主动取消依旧会取消 handler ctx
+func (d *HandlerDeadline) WithContext(ctx context.Context, fullMethod string) (context.Context, context.CancelFunc) {
+	timeout, detach := d.Timeout(ctx, fullMethod)
+	if timeout <= 0 {
+		return ctx, func() {}
+	}
+	if !detach {
+		return context.WithTimeout(ctx, timeout)
+	}
+
+	parent := ctx
+	ctx, cancel := context.WithTimeout(ectx.NewNoCancelContext(parent), timeout)
+	stop := context.AfterFunc(parent, func() {
+		if parent.Err() == context.Canceled {
+			cancel()
+		}
+	})
+	return ctx, func() {
+		stop()
+		cancel()
+	}
+}
+
+// UnaryDeadlineInterceptor 按照配置设置 handler 超时时间, 超时立即返回 DeadlineExceeded
+func UnaryDeadlineInterceptor(config *grpc_server_config.Config) grpc.UnaryServerInterceptor {
+	d := NewHandlerDeadline(config)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, cancel := d.WithContext(ctx, info.FullMethod)
+		defer cancel()
+
+		if _, ok := ctx.Deadline(); !ok {
+			return handler(ctx, req)
+		}
+		return runWithContext(ctx, req, handler)
+	}
+}
+
+// StreamDeadlineInterceptor 按照配置设置 stream ctx 超时时间
+// stream handler 会并发读写 stream, 无法像 unary 一样提前返回, 需要 handler 自行响应 ctx
+func StreamDeadlineInterceptor(config *grpc_server_config.Config) grpc.StreamServerInterceptor {
+	d := NewHandlerDeadline(config)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, cancel := d.WithContext(ss.Context(), info.FullMethod)
+		defer cancel()
+
+		stream := WrapServerStream(ss)
+		stream.SetContext(ctx)
+		return contextStatus(handler(srv, stream))
+	}
+}
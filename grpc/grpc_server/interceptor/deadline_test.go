@@ -0,0 +1,115 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestHandlerDeadline_MethodTimeout(t *testing.T) {
+	d := NewHandlerDeadline(&grpc_server_config.Config{
+		MethodTimeouts: map[string]time.Duration{
+			"/user.UserService/GetUserInfo": time.Second,
+			"/user.userservice/*":           2 * time.Second,
+			"*":                             3 * time.Second,
+		},
+	})
+	v, ok := d.MethodTimeout("/user.UserService/GetUserInfo")
+	assert.True(t, ok)
+	assert.Equal(t, time.Second, v)
+	v, ok = d.MethodTimeout("/user.UserService/Other")
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Second, v)
+	v, ok = d.MethodTimeout("/order.OrderService/Get")
+	assert.True(t, ok)
+	assert.Equal(t, 3*time.Second, v)
+}
+
+func TestHandlerDeadline_Timeout(t *testing.T) {
+	d := NewHandlerDeadline(&grpc_server_config.Config{
+		MinDeadlineDuration: time.Second,
+		MaxDeadlineDuration: 10 * time.Second,
+		MethodTimeouts: map[string]time.Duration{
+			"/a.A/Fast": 100 * time.Millisecond,
+		},
+	})
+
+	// 没有客户端 deadline 时使用最长超时时间
+	timeout, detach := d.Timeout(context.Background(), "/a.A/B")
+	assert.Equal(t, 10*time.Second, timeout)
+	assert.False(t, detach)
+
+	// 客户端 deadline 小于最短超时时间
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	timeout, detach = d.Timeout(ctx, "/a.A/B")
+	assert.Equal(t, time.Second, timeout)
+	assert.True(t, detach)
+
+	// 方法级超时时间优先
+	timeout, detach = d.Timeout(ctx, "/a.A/Fast")
+	assert.Equal(t, 100*time.Millisecond, timeout)
+	assert.True(t, detach)
+
+	timeout, detach = d.Timeout(context.Background(), "/a.A/Fast")
+	assert.Equal(t, 100*time.Millisecond, timeout)
+	assert.False(t, detach)
+}
+
+func TestHandlerDeadline_WithContext(t *testing.T) {
+	d := NewHandlerDeadline(&grpc_server_config.Config{MinDeadlineDuration: time.Second})
+
+	parent, cancelParent := context.WithTimeout(context.Background(), time.Millisecond)
+	defer cancelParent()
+	ctx, cancel := d.WithContext(parent, "/a.A/B")
+	defer cancel()
+	<-parent.Done()
+	assert.Nil(t, ctx.Err())
+
+	parent, cancelParent = context.WithCancel(context.Background())
+	parent, cancelDeadline := context.WithTimeout(parent, 500*time.Millisecond)
+	defer cancelDeadline()
+	ctx, cancel = d.WithContext(parent, "/a.A/B")
+	defer cancel()
+	cancelParent()
+	<-ctx.Done()
+	assert.Equal(t, context.Canceled, ctx.Err())
+}
+
+func TestUnaryDeadlineInterceptor(t *testing.T) {
+	interceptor := UnaryDeadlineInterceptor(&grpc_server_config.Config{
+		MethodTimeouts: map[string]time.Duration{"/a.A/Slow": 10 * time.Millisecond},
+	})
+	_, err := interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/a.A/Slow"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			time.Sleep(50 * time.Millisecond)
+			return nil, nil
+		})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/a.A/B"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			_, ok := ctx.Deadline()
+			assert.False(t, ok)
+			return nil, nil
+		})
+	assert.Nil(t, err)
+}
+
+func TestStreamDeadlineInterceptor(t *testing.T) {
+	interceptor := StreamDeadlineInterceptor(&grpc_server_config.Config{
+		MethodTimeouts: map[string]time.Duration{"*": 10 * time.Millisecond},
+	})
+	ss := &mockServerStream{ctx: context.Background()}
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/a.A/B"}, func(srv interface{}, stream grpc.ServerStream) error {
+		<-stream.Context().Done()
+		return stream.Context().Err()
+	})
+	assert.Equal(t, codes.DeadlineExceeded, status.Code(err))
+}
@@ -16,3 +16,15 @@ func GrpcHeaderCarrierInterceptor() grpc.UnaryServerInterceptor {
 		return handler(ctx, req)
 	}
 }
+
+func GrpcStreamHeaderCarrierInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := ss.Context()
+		if md, ok := metadata.FromIncomingContext(ctx); ok {
+			stream := WrapServerStream(ss)
+			stream.SetContext(transport.CustomKeysMapPropagator.Extract(ctx, transport.GrpcHeaderCarrier(md)))
+			return handler(srv, stream)
+		}
+		return handler(srv, ss)
+	}
+}
@@ -8,7 +8,9 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/google/uuid"
+	"github.com/weblazy/easy/code_err"
 	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/etls"
 	"github.com/weblazy/easy/etrace"
 	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
 	"google.golang.org/grpc"
@@ -40,11 +42,17 @@ func GrpcLogger(config *grpc_server_config.Config) grpc.UnaryServerInterceptor {
 		}
 		fields := make([]zap.Field, 0)
 		fields = append(fields, elog.FieldMethod(info.FullMethod), elog.FieldReq(req), zap.Any("metadata", md))
+		if cn := etls.GetPeerCommonName(ctx); cn != "" {
+			fields = append(fields, zap.String("peer_cn", cn))
+		}
 
 		resp, err = handler(ctx, req)
 		ctx = elog.SetLogerName(ctx, grpc_server_config.PkgName)
 		if err != nil {
 			fields = append(fields, elog.FieldError(err), elog.FieldDuration(time.Since(start)))
+			if codeErr, ok := code_err.FromError(err); ok {
+				fields = append(fields, zap.Int64("biz_code", codeErr.Code), zap.String("debug_msg", codeErr.DebugMsg))
+			}
 			elog.ErrorCtx(ctx, grpc_server_config.PkgName, fields...)
 		} else {
 			fields = append(fields, elog.FieldResp(resp), elog.FieldDuration(time.Since(start)))
@@ -54,6 +62,40 @@ func GrpcLogger(config *grpc_server_config.Config) grpc.UnaryServerInterceptor {
 	}
 }
 
+// GrpcStreamLogger stream 访问日志, 在 stream 结束时记录一次, 包含收发消息数
+func GrpcStreamLogger(config *grpc_server_config.Config) grpc.StreamServerInterceptor {
+	once.Do(config.InitLogger)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		start := time.Now()
+		stream := WrapServerStream(ss)
+
+		md, _ := metadata.FromIncomingContext(stream.Context())
+		fields := make([]zap.Field, 0)
+		fields = append(fields, elog.FieldMethod(info.FullMethod), zap.String("type", StreamType(info)), zap.Any("metadata", md))
+		if cn := etls.GetPeerCommonName(stream.Context()); cn != "" {
+			fields = append(fields, zap.String("peer_cn", cn))
+		}
+
+		err = handler(srv, stream)
+		ctx := elog.SetLogerName(stream.Context(), grpc_server_config.PkgName)
+		fields = append(fields,
+			zap.Int64("recv_msgs", stream.RecvMsgs()),
+			zap.Int64("sent_msgs", stream.SentMsgs()),
+			elog.FieldDuration(time.Since(start)),
+		)
+		if err != nil {
+			fields = append(fields, elog.FieldError(err))
+			if codeErr, ok := code_err.FromError(err); ok {
+				fields = append(fields, zap.Int64("biz_code", codeErr.Code), zap.String("debug_msg", codeErr.DebugMsg))
+			}
+			elog.ErrorCtx(ctx, grpc_server_config.PkgName, fields...)
+		} else {
+			elog.InfoCtx(ctx, grpc_server_config.PkgName, fields...)
+		}
+		return err
+	}
+}
+
 // func GrpcLoggerLite() grpc.UnaryServerInterceptor {
 // 	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp interface{}, err error) {
 // 		// otel trace
@@ -40,3 +40,23 @@ func MetricUnaryServerInterceptor(successCodes []string) grpc.UnaryServerInterce
 		return
 	}
 }
+
+func MetricStreamServerInterceptor(successCodes []string) grpc.StreamServerInterceptor {
+	grpc_prometheus.EnableHandlingTimeHistogram()
+	// 每条消息的收发数由 grpc_prometheus 统计 (grpc_server_msg_received_total, grpc_server_msg_sent_total)
+	originMw := grpc_prometheus.StreamServerInterceptor
+	extractor := eerror.ExtractBizCode(successCodes)
+
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		err = originMw(srv, ss, info, handler)
+		st, _ := status.FromError(err)
+
+		// stream 没有单一的 resp, 只按 err 计算 biz code
+		bizCode, ok := extractor(nil, err)
+		if ok {
+			service, method := fmetric.SplitGrpcMethodName(info.FullMethod)
+			ServerWithBizHandledCounter.WithLabelValues(StreamType(info), service, method, st.Code().String(), bizCode).Inc()
+		}
+		return
+	}
+}
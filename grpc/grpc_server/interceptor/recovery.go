@@ -26,3 +26,8 @@ func UnaryRecoveryInterceptor() grpc.UnaryServerInterceptor {
 	return grpc_recovery.UnaryServerInterceptor(
 		grpc_recovery.WithRecoveryHandlerContext(GrpcRecoveryHandler))
 }
+
+func StreamRecoveryInterceptor() grpc.StreamServerInterceptor {
+	return grpc_recovery.StreamServerInterceptor(
+		grpc_recovery.WithRecoveryHandlerContext(GrpcRecoveryHandler))
+}
@@ -0,0 +1,80 @@
+package interceptor
+
+import (
+	"context"
+	"sync/atomic"
+
+	"google.golang.org/grpc"
+)
+
+const (
+	GrpcTypeUnary        = "unary"
+	GrpcTypeClientStream = "client_stream"
+	GrpcTypeServerStream = "server_stream"
+	GrpcTypeBidiStream   = "bidi_stream"
+)
+
+// StreamType 返回 stream 类型, 与 grpc_prometheus 的 grpc_type label 保持一致
+func StreamType(info *grpc.StreamServerInfo) string {
+	if info.IsClientStream && info.IsServerStream {
+		return GrpcTypeBidiStream
+	}
+	if info.IsClientStream {
+		return GrpcTypeClientStream
+	}
+	return GrpcTypeServerStream
+}
+
+// MonitorServerStream 包装 grpc.ServerStream, 支持替换 ctx 并统计收发消息数
+type MonitorServerStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	recvMsgs int64
+	sentMsgs int64
+}
+
+// WrapServerStream 包装 grpc.ServerStream, 已经包装过的直接返回
+func WrapServerStream(ss grpc.ServerStream) *MonitorServerStream {
+	if existing, ok := ss.(*MonitorServerStream); ok {
+		return existing
+	}
+	return &MonitorServerStream{ServerStream: ss, ctx: ss.Context()}
+}
+
+// Context 返回替换后的 ctx
+func (s *MonitorServerStream) Context() context.Context {
+	return s.ctx
+}
+
+// SetContext 替换 stream 的 ctx, 用于在拦截器中向下游传递数据
+func (s *MonitorServerStream) SetContext(ctx context.Context) {
+	s.ctx = ctx
+}
+
+// RecvMsg 接收消息并计数
+func (s *MonitorServerStream) RecvMsg(m interface{}) error {
+	err := s.ServerStream.RecvMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.recvMsgs, 1)
+	}
+	return err
+}
+
+// SendMsg 发送消息并计数
+func (s *MonitorServerStream) SendMsg(m interface{}) error {
+	err := s.ServerStream.SendMsg(m)
+	if err == nil {
+		atomic.AddInt64(&s.sentMsgs, 1)
+	}
+	return err
+}
+
+// RecvMsgs 已接收的消息数
+func (s *MonitorServerStream) RecvMsgs() int64 {
+	return atomic.LoadInt64(&s.recvMsgs)
+}
+
+// SentMsgs 已发送的消息数
+func (s *MonitorServerStream) SentMsgs() int64 {
+	return atomic.LoadInt64(&s.sentMsgs)
+}
@@ -0,0 +1,90 @@
+package interceptor
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
+	"github.com/weblazy/easy/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+type mockServerStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	recv []string
+}
+
+func (s *mockServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *mockServerStream) RecvMsg(m interface{}) error {
+	if len(s.recv) == 0 {
+		return context.Canceled
+	}
+	*(m.(*string)) = s.recv[0]
+	s.recv = s.recv[1:]
+	return nil
+}
+
+func (s *mockServerStream) SendMsg(m interface{}) error {
+	return nil
+}
+
+func TestStreamType(t *testing.T) {
+	assert.Equal(t, GrpcTypeServerStream, StreamType(&grpc.StreamServerInfo{IsServerStream: true}))
+	assert.Equal(t, GrpcTypeClientStream, StreamType(&grpc.StreamServerInfo{IsClientStream: true}))
+	assert.Equal(t, GrpcTypeBidiStream, StreamType(&grpc.StreamServerInfo{IsClientStream: true, IsServerStream: true}))
+}
+
+func TestWrapServerStream(t *testing.T) {
+	ss := &mockServerStream{ctx: context.Background(), recv: []string{"a", "b"}}
+	stream := WrapServerStream(ss)
+	assert.Same(t, stream, WrapServerStream(stream))
+
+	var msg string
+	assert.Nil(t, stream.RecvMsg(&msg))
+	assert.Nil(t, stream.RecvMsg(&msg))
+	assert.NotNil(t, stream.RecvMsg(&msg))
+	assert.Nil(t, stream.SendMsg(msg))
+	assert.EqualValues(t, 2, stream.RecvMsgs())
+	assert.EqualValues(t, 1, stream.SentMsgs())
+}
+
+func TestGrpcStreamHeaderCarrierInterceptor(t *testing.T) {
+	md := metadata.Pairs(transport.PrefixPass+"uid", "1")
+	ss := &mockServerStream{ctx: metadata.NewIncomingContext(context.Background(), md)}
+	interceptor := GrpcStreamHeaderCarrierInterceptor()
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/a.A/B"}, func(srv interface{}, stream grpc.ServerStream) error {
+		assert.Equal(t, "1", transport.GetMapFromContext(stream.Context())[transport.PrefixPass+"uid"])
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestGrpcStreamLogger(t *testing.T) {
+	ss := &mockServerStream{ctx: context.Background(), recv: []string{"a"}}
+	interceptor := GrpcStreamLogger(grpc_server_config.DefaultConfig())
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/a.A/B", IsServerStream: true}, func(srv interface{}, stream grpc.ServerStream) error {
+		var msg string
+		assert.Nil(t, stream.RecvMsg(&msg))
+		assert.Nil(t, stream.SendMsg(msg))
+		assert.EqualValues(t, 1, WrapServerStream(stream).RecvMsgs())
+		return nil
+	})
+	assert.Nil(t, err)
+}
+
+func TestStreamRecoveryInterceptor(t *testing.T) {
+	ss := &mockServerStream{ctx: context.Background()}
+	interceptor := StreamRecoveryInterceptor()
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/a.A/B"}, func(srv interface{}, stream grpc.ServerStream) error {
+		panic("any")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err))
+}
@@ -22,42 +22,58 @@ func UnaryTimeoutInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor
 		ctx, cancel := context.WithTimeout(ctx, timeout)
 		defer cancel()
 
-		var resp interface{}
-		var err error
-		var lock sync.Mutex
-		done := make(chan struct{})
-		// create channel with buffer size 1 to avoid goroutine leak
-		panicChan := make(chan interface{}, 1)
-		go func() {
-			defer func() {
-				if p := recover(); p != nil {
-					// attach call stack to avoid missing in different goroutine
-					panicChan <- fmt.Sprintf("%+v\n\n%s", p, strings.TrimSpace(string(debug.Stack())))
-				}
-			}()
-
-			lock.Lock()
-			defer lock.Unlock()
-			resp, err = handler(ctx, req)
-			close(done)
-		}()
+		return runWithContext(ctx, req, handler)
+	}
+}
+
+// contextStatus 将 handler 直接返回的 ctx 错误转换为 grpc status
+func contextStatus(err error) error {
+	switch err {
+	case context.Canceled:
+		return status.Error(codes.Canceled, err.Error())
+	case context.DeadlineExceeded:
+		return status.Error(codes.DeadlineExceeded, err.Error())
+	}
+	return err
+}
 
-		select {
-		case p := <-panicChan:
-			panic(p)
-		case <-done:
-			lock.Lock()
-			defer lock.Unlock()
-			return resp, err
-		case <-ctx.Done():
-			err := ctx.Err()
-
-			if err == context.Canceled {
-				err = status.Error(codes.Canceled, err.Error())
-			} else if err == context.DeadlineExceeded {
-				err = status.Error(codes.DeadlineExceeded, err.Error())
+// runWithContext 在独立的 goroutine 中执行 handler, ctx 结束时立即返回, 不等待 handler 执行完成
+func runWithContext(ctx context.Context, req interface{}, handler grpc.UnaryHandler) (interface{}, error) {
+	var resp interface{}
+	var err error
+	var lock sync.Mutex
+	done := make(chan struct{})
+	// create channel with buffer size 1 to avoid goroutine leak
+	panicChan := make(chan interface{}, 1)
+	go func() {
+		defer func() {
+			if p := recover(); p != nil {
+				// attach call stack to avoid missing in different goroutine
+				panicChan <- fmt.Sprintf("%+v\n\n%s", p, strings.TrimSpace(string(debug.Stack())))
 			}
-			return nil, err
+		}()
+
+		lock.Lock()
+		defer lock.Unlock()
+		resp, err = handler(ctx, req)
+		close(done)
+	}()
+
+	select {
+	case p := <-panicChan:
+		panic(p)
+	case <-done:
+		lock.Lock()
+		defer lock.Unlock()
+		return resp, err
+	case <-ctx.Done():
+		err := ctx.Err()
+
+		if err == context.Canceled {
+			err = status.Error(codes.Canceled, err.Error())
+		} else if err == context.DeadlineExceeded {
+			err = status.Error(codes.DeadlineExceeded, err.Error())
 		}
+		return nil, err
 	}
 }
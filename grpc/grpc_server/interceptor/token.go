@@ -0,0 +1,116 @@
+package interceptor
+
+import (
+	"context"
+
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/grpc/grpc_method"
+	"github.com/weblazy/easy/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// metadata key 统一为小写, 与 http 的 X-Token, X-Uid, X-Debug 对应
+const (
+	TokenHeader = "x-token"
+	UidHeader   = "x-uid"
+	DebugHeader = "x-debug"
+	// UidPassKey uid 写入透传参数的 key, 调用下游服务时自动透传
+	UidPassKey = transport.PrefixPass + "uid"
+)
+
+type uidKey struct{}
+
+// GetUid 获取 token 校验通过后的 uid
+func GetUid(ctx context.Context) string {
+	uid, _ := ctx.Value(uidKey{}).(string)
+	return uid
+}
+
+// TokenAuth grpc token 校验, 与 http 的 interceptor.Token 对应
+type TokenAuth struct {
+	validateToken func(token string) (uid string, err error)
+	publicMethods grpc_method.MethodSet
+}
+
+// NewTokenAuth 创建 token 校验, publicMethods 为不需要校验的方法, 支持 /package.Service/* 通配
+func NewTokenAuth(validateToken func(token string) (uid string, err error), publicMethods ...string) *TokenAuth {
+	return &TokenAuth{
+		validateToken: validateToken,
+		publicMethods: grpc_method.NewMethodSet(publicMethods...),
+	}
+}
+
+// Auth 校验 metadata 中的 token, 通过后将 uid 写入 ctx 和透传参数
+func (a *TokenAuth) Auth(ctx context.Context, fullMethod string) (context.Context, error) {
+	if a.publicMethods.Has(fullMethod) {
+		return ctx, nil
+	}
+	md, _ := metadata.FromIncomingContext(ctx)
+	var uid string
+	if isDebug(md) {
+		uid = mdValue(md, UidHeader)
+	} else {
+		token := mdValue(md, TokenHeader)
+		if token == "" {
+			elog.InfoCtx(ctx, "grpc token 不存在", elog.FieldMethod(fullMethod))
+			return ctx, TokenError()
+		}
+		var err error
+		uid, err = a.validateToken(token)
+		if err != nil {
+			elog.InfoCtx(ctx, "grpc token 校验失败", elog.FieldMethod(fullMethod), elog.FieldError(err))
+			return ctx, TokenError()
+		}
+	}
+	ctx = context.WithValue(ctx, uidKey{}, uid)
+	return transport.WithValue(ctx, UidPassKey, uid), nil
+}
+
+// UnaryTokenInterceptor unary token 校验
+func UnaryTokenInterceptor(validateToken func(token string) (uid string, err error), publicMethods ...string) grpc.UnaryServerInterceptor {
+	a := NewTokenAuth(validateToken, publicMethods...)
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		ctx, err := a.Auth(ctx, info.FullMethod)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamTokenInterceptor stream token 校验
+func StreamTokenInterceptor(validateToken func(token string) (uid string, err error), publicMethods ...string) grpc.StreamServerInterceptor {
+	a := NewTokenAuth(validateToken, publicMethods...)
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx, err := a.Auth(ss.Context(), info.FullMethod)
+		if err != nil {
+			return err
+		}
+		stream := WrapServerStream(ss)
+		stream.SetContext(ctx)
+		return handler(srv, stream)
+	}
+}
+
+// TokenError 返回 codes.Unauthenticated, detail 为 code_err.TokenErr
+func TokenError() error {
+	return code_err.TokenErr.GRPCStatus().Err()
+}
+
+// isDebug 与 http 一致, 开启 BaseConfig.Debug 且 x-debug 与 BaseConfig.XDebugKey 相同时跳过校验
+func isDebug(md metadata.MD) bool {
+	if econfig.GlobalViper == nil || !econfig.GlobalViper.GetBool("BaseConfig.Debug") {
+		return false
+	}
+	return mdValue(md, DebugHeader) == econfig.GlobalViper.GetString("BaseConfig.XDebugKey")
+}
+
+func mdValue(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
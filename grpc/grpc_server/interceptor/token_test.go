@@ -0,0 +1,74 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/grpc/proto/response"
+	"github.com/weblazy/easy/transport"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func validateToken(token string) (string, error) {
+	if token == "valid" {
+		return "1", nil
+	}
+	return "", errors.New("invalid token")
+}
+
+func TestUnaryTokenInterceptor(t *testing.T) {
+	interceptor := UnaryTokenInterceptor(validateToken, "/user.UserService/Login")
+	info := &grpc.UnaryServerInfo{FullMethod: "/user.UserService/GetUserInfo"}
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TokenHeader, "valid"))
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		assert.Equal(t, "1", GetUid(ctx))
+		assert.Equal(t, "1", transport.GetMapFromContext(ctx)[UidPassKey])
+		return nil, nil
+	})
+	assert.Nil(t, err)
+
+	for _, ctx := range []context.Context{
+		context.Background(),
+		metadata.NewIncomingContext(context.Background(), metadata.Pairs(TokenHeader, "invalid")),
+	} {
+		_, err = interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			t.Fatal("handler should not be called")
+			return nil, nil
+		})
+		st := status.Convert(err)
+		assert.Equal(t, codes.Unauthenticated, st.Code())
+		assert.Len(t, st.Details(), 1)
+		detail := st.Details()[0].(*response.CodeError)
+		assert.Equal(t, code_err.TokenErr.Code, detail.Code)
+	}
+
+	_, err = interceptor(context.Background(), nil, &grpc.UnaryServerInfo{FullMethod: "/user.UserService/Login"},
+		func(ctx context.Context, req interface{}) (interface{}, error) {
+			assert.Equal(t, "", GetUid(ctx))
+			return nil, nil
+		})
+	assert.Nil(t, err)
+}
+
+func TestStreamTokenInterceptor(t *testing.T) {
+	interceptor := StreamTokenInterceptor(validateToken)
+	ss := &mockServerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.Pairs(TokenHeader, "valid"))}
+	err := interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/a.A/B"}, func(srv interface{}, stream grpc.ServerStream) error {
+		assert.Equal(t, "1", GetUid(stream.Context()))
+		return nil
+	})
+	assert.Nil(t, err)
+
+	ss = &mockServerStream{ctx: context.Background()}
+	err = interceptor(nil, ss, &grpc.StreamServerInfo{FullMethod: "/a.A/B"}, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+}
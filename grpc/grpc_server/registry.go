@@ -0,0 +1,48 @@
+package grpc_server
+
+import (
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/econfig/nacos"
+)
+
+// initRegistrar 根据监听的端口创建服务注册, bufnet 不注册
+func (c *GrpcServer) initRegistrar() {
+	if !c.config.EnableRegistry || c.IsBufNet() {
+		return
+	}
+	config := c.config.Registry
+	if config.ServiceName == "" {
+		config.ServiceName = c.config.Name
+	}
+	if config.Port == 0 {
+		config.Port = uint64(c.config.Port)
+	}
+	if config.Ip == "" && c.config.Host != "0.0.0.0" {
+		config.Ip = c.config.Host
+	}
+	c.registrar = nacos.NewRegistrar(config, nil)
+}
+
+// register 注册到 nacos, 程序退出时按 closes.RegistryPriority 最先注销
+func (c *GrpcServer) register() error {
+	if c.registrar == nil {
+		return nil
+	}
+	if err := c.registrar.Register(); err != nil {
+		return err
+	}
+	closes.AddShutdown(closes.ModuleClose{
+		Name:     "grpc_server deregister",
+		Priority: closes.RegistryPriority,
+		Func:     c.deregister,
+	})
+	return nil
+}
+
+// deregister 从 nacos 注销, 错误只记录日志
+func (c *GrpcServer) deregister() {
+	if c.registrar == nil {
+		return
+	}
+	_ = c.registrar.Deregister()
+}
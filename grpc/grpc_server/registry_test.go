@@ -0,0 +1,45 @@
+package grpc_server
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/nacos-group/nacos-sdk-go/model"
+	"github.com/nacos-group/nacos-sdk-go/vo"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/econfig/nacos"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
+)
+
+func TestGrpcServer_Registry(t *testing.T) {
+	naming := nacos.SetLocalNaming()
+	defer nacos.SetNamingClient(nil)
+
+	cfg := grpc_server_config.DefaultConfig()
+	cfg.Name = "user"
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 0
+	cfg.EnableRegistry = true
+	cfg.Registry.Version = "v2"
+	cfg.Registry.GrayTag = "canary"
+	server := NewGrpcServer(cfg)
+	assert.Nil(t, server.Init())
+	go server.Start()
+
+	selectInstances := func() []model.Instance {
+		instances, err := naming.SelectInstances(vo.SelectInstancesParam{ServiceName: "user", HealthyOnly: true})
+		assert.Nil(t, err)
+		return instances
+	}
+	assert.Eventually(t, func() bool { return len(selectInstances()) == 1 }, time.Second, 10*time.Millisecond)
+	instance := selectInstances()[0]
+	assert.Equal(t, "127.0.0.1", instance.Ip)
+	assert.Equal(t, uint64(cfg.Port), instance.Port)
+	assert.Equal(t, "v2", instance.Metadata[nacos.MetadataVersion])
+	assert.Equal(t, "canary", instance.Metadata[nacos.MetadataGray])
+
+	// 优雅关闭时先注销实例
+	assert.Nil(t, server.GracefulStop(context.Background()))
+	assert.Empty(t, selectInstances())
+}
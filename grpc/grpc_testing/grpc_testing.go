@@ -0,0 +1,92 @@
+package grpc_testing
+
+import (
+	"testing"
+
+	"github.com/weblazy/easy/grpc/grpc_client"
+	"github.com/weblazy/easy/grpc/grpc_client/grpc_client_config"
+	"github.com/weblazy/easy/grpc/grpc_client/interceptor"
+	"github.com/weblazy/easy/grpc/grpc_server"
+	"github.com/weblazy/easy/grpc/grpc_server/grpc_server_config"
+	"google.golang.org/grpc"
+)
+
+// BufNetAddr bufnet client 的连接地址, 使用 passthrough 避免 dns 解析
+const BufNetAddr = "passthrough:///bufnet"
+
+// NewBufNetServer 创建并初始化 bufnet GrpcServer, 需要在 Start 之前注册服务
+func NewBufNetServer(config *grpc_server_config.Config) (*grpc_server.GrpcServer, error) {
+	if config == nil {
+		config = grpc_server_config.DefaultConfig()
+	}
+	config.Network = "bufnet"
+	server := grpc_server.NewGrpcServer(config)
+	if err := server.Init(); err != nil {
+		return nil, err
+	}
+	return server, nil
+}
+
+// NewBufNetClient 创建连接 server 的 GrpcClient, 使用与正式环境相同的拦截器
+// server 需要已经 Init, 未开启 TLS 时强制使用非安全传输
+func NewBufNetClient(server *grpc_server.GrpcServer, config *grpc_client_config.Config) *grpc_client.GrpcClient {
+	if config == nil {
+		config = grpc_client_config.DefaultConfig()
+	}
+	config.Addr = BufNetAddr
+	if !config.TLSConfig().Enable() {
+		config.EnableWithInsecure = true
+	}
+	config.DialOptions = append(config.DialOptions, grpc.WithContextDialer(server.Dialer()))
+	return grpc_client.NewGrpcClient(config)
+}
+
+// NewBufNetPair 启动 bufnet GrpcServer 并返回连接该 server 的 GrpcClient, 测试结束时自动关闭
+// register 用于在 server 启动前注册服务, 如 user.RegisterUserServiceServer(s, &User{})
+func NewBufNetPair(t testing.TB, serverConfig *grpc_server_config.Config, clientConfig *grpc_client_config.Config, register func(s *grpc_server.GrpcServer)) (*grpc_server.GrpcServer, *grpc_client.GrpcClient) {
+	t.Helper()
+	server, err := NewBufNetServer(serverConfig)
+	if err != nil {
+		t.Fatalf("init bufnet grpc server: %v", err)
+	}
+	if register != nil {
+		register(server)
+	}
+	go func() {
+		_ = server.Start()
+	}()
+	t.Cleanup(func() {
+		_ = server.Stop()
+	})
+
+	client := NewBufNetClient(server, clientConfig)
+	if client.Error() != nil {
+		t.Fatalf("dial bufnet grpc server: %v", client.Error())
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return server, client
+}
+
+// NewReplayClient 创建回放模式的 GrpcClient, 从 dir 中录制的 golden 文件返回响应, 不连接下游
+// 录制时在 client 配置中设置 Replay: &interceptor.ReplayConf{Mode: interceptor.ReplayModeRecord, Dir: dir}
+func NewReplayClient(t testing.TB, dir string, matchFields ...string) *grpc_client.GrpcClient {
+	t.Helper()
+	config := grpc_client_config.DefaultConfig()
+	config.Addr = "passthrough:///replay"
+	config.EnableWithInsecure = true
+	config.Replay = &interceptor.ReplayConf{
+		Mode:        interceptor.ReplayModeReplay,
+		Dir:         dir,
+		MatchFields: matchFields,
+	}
+	client := grpc_client.NewGrpcClient(config)
+	if client.Error() != nil {
+		t.Fatalf("new replay grpc client: %v", client.Error())
+	}
+	t.Cleanup(func() {
+		_ = client.Close()
+	})
+	return client
+}
@@ -0,0 +1,69 @@
+package grpc_testing
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/grpc/grpc_client/grpc_client_config"
+	"github.com/weblazy/easy/grpc/grpc_client/interceptor"
+	"github.com/weblazy/easy/grpc/grpc_server"
+	"github.com/weblazy/easy/grpc/proto/user"
+)
+
+type User struct {
+	user.UnimplementedUserServiceServer
+}
+
+func (*User) GetUserInfo(ctx context.Context, req *user.GetUserInfoRequest) (*user.GetUserInfoResponse, error) {
+	if req.Uid == 0 {
+		return nil, code_err.ParamsErr.WithDebugMsg("uid required")
+	}
+	return &user.GetUserInfoResponse{
+		Detail: &user.User{
+			Uid:  req.Uid,
+			Name: "lazy",
+		},
+	}, nil
+}
+
+func TestNewBufNetPair(t *testing.T) {
+	_, client := NewBufNetPair(t, nil, nil, func(s *grpc_server.GrpcServer) {
+		user.RegisterUserServiceServer(s, &User{})
+	})
+	userClient := user.NewUserServiceClient(client)
+
+	resp, err := userClient.GetUserInfo(context.Background(), &user.GetUserInfoRequest{Uid: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "lazy", resp.GetDetail().GetName())
+
+	_, err = userClient.GetUserInfo(context.Background(), &user.GetUserInfoRequest{})
+	assert.Equal(t, code_err.ParamsErr, code_err.GetCodeErr(err))
+}
+
+func TestNewReplayClient(t *testing.T) {
+	dir := t.TempDir()
+	clientConfig := grpc_client_config.DefaultConfig()
+	clientConfig.Replay = &interceptor.ReplayConf{Mode: interceptor.ReplayModeRecord, Dir: dir}
+	_, client := NewBufNetPair(t, nil, clientConfig, func(s *grpc_server.GrpcServer) {
+		user.RegisterUserServiceServer(s, &User{})
+	})
+	userClient := user.NewUserServiceClient(client)
+	_, err := userClient.GetUserInfo(context.Background(), &user.GetUserInfoRequest{Uid: 1})
+	assert.Nil(t, err)
+	_, err = userClient.GetUserInfo(context.Background(), &user.GetUserInfoRequest{})
+	assert.NotNil(t, err)
+
+	// 回放时不需要 server
+	userClient = user.NewUserServiceClient(NewReplayClient(t, dir))
+	resp, err := userClient.GetUserInfo(context.Background(), &user.GetUserInfoRequest{Uid: 1})
+	assert.Nil(t, err)
+	assert.Equal(t, "lazy", resp.GetDetail().GetName())
+
+	_, err = userClient.GetUserInfo(context.Background(), &user.GetUserInfoRequest{})
+	assert.Equal(t, code_err.ParamsErr, code_err.GetCodeErr(err))
+
+	_, err = userClient.GetUserInfo(context.Background(), &user.GetUserInfoRequest{Uid: 2})
+	assert.ErrorIs(t, err, interceptor.ErrReplayUnmatched)
+}
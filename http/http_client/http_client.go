@@ -45,6 +45,15 @@ func NewHttpClient(c *http_client_config.Config) *HttpClient {
 		AddInterceptors(client, onBefore, onAfter, onErr)
 	}
 
+	if c.EnableBreakerInterceptor {
+		name := c.Name
+		if name == "" {
+			name = c.Addr
+		}
+		onBefore, onAfter, onErr := interceptor.BreakerInterceptor(name, c.Breaker, c.MetricPathRewriter)
+		AddInterceptors(client, onBefore, onAfter, onErr)
+	}
+
 	return &HttpClient{
 		Client:  client,
 		Request: client.R(),
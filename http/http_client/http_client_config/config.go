@@ -4,6 +4,8 @@ import (
 	"crypto/tls"
 	"runtime"
 	"time"
+
+	"github.com/weblazy/easy/ebreaker"
 )
 
 const (
@@ -26,6 +28,9 @@ type Config struct {
 	EnableMetricInterceptor bool               // 是否开启 metric, 默认关闭
 	MetricPathRewriter      MetricPathRewriter // 指标监控 path 重写方法, 防止 metrics label 不可控
 
+	EnableBreakerInterceptor bool             // 是否开启熔断, 默认关闭, 熔断器按照请求方法和 MetricPathRewriter 重写后的 path 区分
+	Breaker                  *ebreaker.Config // 熔断配置, 默认 10s 窗口内请求数超过 100 时开始按成功率丢弃请求
+
 	EnableTraceInterceptor           bool // 是否开启链路追踪，默认开启
 	EnableAccessInterceptor          bool // 是否开启记录请求数据，默认开启
 	EnableAccessInterceptorReq       bool // 是否开启记录请求参数，默认开启
@@ -50,6 +55,7 @@ func DefaultConfig() *Config {
 		EnableAccessInterceptorReq: true,
 		EnableAccessInterceptorRes: true,
 		MetricPathRewriter:         DefaultMetricPathRewriter,
+		Breaker:                    ebreaker.DefaultConfig(),
 	}
 }
 
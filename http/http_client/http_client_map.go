@@ -0,0 +1,41 @@
+package http_client
+
+import (
+	"sync"
+
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/http/http_client/http_client_config"
+)
+
+var (
+	HttpClientMap sync.Map
+	httpClientMu  sync.Mutex
+)
+
+// GetHttpClient 按照配置 key 获取 http 客户端, 不存在时从 econfig.GlobalViper 读取配置创建
+func GetHttpClient(name string) *HttpClient {
+	if v, ok := HttpClientMap.Load(name); ok {
+		return v.(*HttpClient)
+	}
+	httpClientMu.Lock()
+	defer httpClientMu.Unlock()
+	if v, ok := HttpClientMap.Load(name); ok {
+		return v.(*HttpClient)
+	}
+
+	conf := http_client_config.DefaultConfig()
+	econfig.GlobalViper.UnmarshalKey(name, conf)
+	if conf.Name == "" {
+		conf.Name = name
+	}
+	client := NewHttpClient(conf)
+
+	HttpClientMap.Store(name, client)
+	ecomponent.Register(ecomponent.KindHttpClient, name, closes.ClientPriority, func() {
+		HttpClientMap.Delete(name)
+		client.GetClient().CloseIdleConnections()
+	})
+	return client
+}
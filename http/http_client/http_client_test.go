@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/smartystreets/goconvey/convey"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/econfig/eviper"
 )
 
 func TestNewHttpClient(t *testing.T) {
@@ -17,3 +20,25 @@ func TestNewHttpClient(t *testing.T) {
 		// 	convey.So(err, convey.ShouldBeNil)
 	})
 }
+
+func TestGetHttpClient(t *testing.T) {
+	convey.Convey("TestGetHttpClient", t, func() {
+		econfig.GlobalViper = eviper.NewViperFromString(`
+[userHttp]
+Addr = "http://127.0.0.1:8080"
+ReadTimeout = "2s"
+`)
+		client := GetHttpClient("userHttp")
+		convey.So(client.BaseURL, convey.ShouldEqual, "http://127.0.0.1:8080")
+		convey.So(client.GetClient().Timeout.String(), convey.ShouldEqual, "2s")
+		convey.So(GetHttpClient("userHttp"), convey.ShouldEqual, client)
+
+		var found bool
+		for _, c := range ecomponent.List() {
+			if c.Kind == ecomponent.KindHttpClient && c.Name == "userHttp" {
+				found = true
+			}
+		}
+		convey.So(found, convey.ShouldBeTrue)
+	})
+}
@@ -0,0 +1,73 @@
+package interceptor
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/weblazy/easy/ebreaker"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/http/http_client/http_client_config"
+	"go.uber.org/zap"
+)
+
+type breakerKey struct{}
+
+// breakerCall 单次请求对应的熔断器, 请求结束时只记录一次结果
+type breakerCall struct {
+	breaker *ebreaker.Breaker
+	marked  bool
+}
+
+func (c *breakerCall) mark(success bool) {
+	if c.marked {
+		return
+	}
+	c.marked = true
+	c.breaker.Mark(success)
+}
+
+// BreakerInterceptor 按照请求方法和 path 熔断, 熔断器打开时返回 ebreaker.ErrNotAllowed
+// 5xx 和网络错误记为失败, path 使用 rewriter 重写, 防止熔断器数量不可控
+func BreakerInterceptor(name string, config *ebreaker.Config, rewriter http_client_config.MetricPathRewriter) (resty.RequestMiddleware, resty.ResponseMiddleware, resty.ErrorHook) {
+	if rewriter == nil {
+		rewriter = http_client_config.DefaultMetricPathRewriter
+	}
+	group := ebreaker.NewGroup("http", name, config)
+
+	beforeFn := func(cli *resty.Client, req *resty.Request) error {
+		// OnBeforeRequest 时还没有 req.RawRequest
+		path := "invalidUrl"
+		if u, err := url.Parse(req.URL); err == nil {
+			path = rewriter(u.Path)
+		}
+		method := req.Method + " " + path
+		breaker := group.Get(method)
+		if err := breaker.Allow(); err != nil {
+			elog.WarnCtx(req.Context(), "http client circuit breaker open", elog.FieldName(name), zap.String("path", method))
+			return err
+		}
+		req.SetContext(context.WithValue(req.Context(), breakerKey{}, &breakerCall{breaker: breaker}))
+		return nil
+	}
+
+	afterFn := func(cli *resty.Client, res *resty.Response) error {
+		if call, ok := res.Request.Context().Value(breakerKey{}).(*breakerCall); ok {
+			call.mark(res.StatusCode() < http.StatusInternalServerError)
+		}
+		return nil
+	}
+
+	errorFn := func(req *resty.Request, err error) {
+		if errors.Is(err, ebreaker.ErrNotAllowed) {
+			return
+		}
+		if call, ok := req.Context().Value(breakerKey{}).(*breakerCall); ok {
+			call.mark(false)
+		}
+	}
+
+	return beforeFn, afterFn, errorFn
+}
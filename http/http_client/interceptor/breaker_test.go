@@ -0,0 +1,50 @@
+package interceptor
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-resty/resty/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/ebreaker"
+)
+
+func TestBreakerInterceptor(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if r.URL.Path == "/error" {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	client := resty.New().SetBaseURL(server.URL)
+	onBefore, onAfter, onErr := BreakerInterceptor("user", &ebreaker.Config{Request: 10}, nil)
+	client.OnBeforeRequest(onBefore)
+	client.OnAfterResponse(onAfter)
+	client.OnError(onErr)
+
+	// 4xx 不会触发熔断
+	for i := 0; i < 100; i++ {
+		resp, err := client.R().Get("/bad")
+		assert.Nil(t, err)
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode())
+	}
+	assert.Equal(t, 100, calls)
+
+	// 5xx 触发熔断
+	var rejected int
+	for i := 0; i < 100; i++ {
+		_, err := client.R().Get("/error")
+		if errors.Is(err, ebreaker.ErrNotAllowed) {
+			rejected++
+		}
+	}
+	assert.Greater(t, rejected, 50)
+	assert.Equal(t, 200-rejected, calls)
+}
@@ -0,0 +1,241 @@
+package http_server
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/pprof"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/env"
+	"github.com/weblazy/easy/http/http_server/http_server_config"
+)
+
+const (
+	// AdminTokenHeader 管理接口的 token, 也可以使用 Authorization: Bearer <token>
+	AdminTokenHeader = "X-Admin-Token"
+	// DefaultAdminPathPrefix 管理接口注册到业务端口时的默认路由前缀
+	DefaultAdminPathPrefix = "/admin"
+
+	redacted = "******"
+)
+
+// adminRetryInterval 热重启时监听管理端口的重试间隔
+var adminRetryInterval = time.Second
+
+// DefaultRedactKeys 配置中默认脱敏的 key 关键字
+var DefaultRedactKeys = []string{"password", "passwd", "pwd", "secret", "token", "key", "dsn", "credential"}
+
+// initAdmin 注册管理接口, 配置了 Port 时使用单独的 gin.Engine, 在 Start 时监听
+// 注册到业务端口时需要在中间件之前调用, 不记录日志和监控, 不受 Timeout 限制
+func (s *HttpServer) initAdmin(r *gin.Engine) error {
+	conf := s.Config.Admin
+	if !conf.Enable {
+		return nil
+	}
+	// 业务端口前面的 nginx 或 sidecar 转发的请求来自本机, 不能只依赖本机访问的限制
+	if conf.Port <= 0 && len(conf.AllowIPs) == 0 && conf.Token == "" {
+		return errors.New("admin on business port requires AllowIPs or Token")
+	}
+	auth, err := adminAuth(conf)
+	if err != nil {
+		return err
+	}
+	var group *gin.RouterGroup
+	if conf.Port > 0 {
+		admin := gin.New()
+		admin.Use(gin.Recovery())
+		s.adminServer = &http.Server{Addr: fmt.Sprintf("%s:%d", s.Config.Host, conf.Port), Handler: admin}
+		group = admin.Group(conf.PathPrefix)
+	} else {
+		prefix := conf.PathPrefix
+		if prefix == "" {
+			prefix = DefaultAdminPathPrefix
+		}
+		group = r.Group(prefix)
+		s.OpenAPI.IgnorePrefix(group.BasePath())
+	}
+
+	redactKeys := conf.RedactKeys
+	if len(redactKeys) == 0 {
+		redactKeys = DefaultRedactKeys
+	}
+	group.Use(auth)
+	group.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	group.GET("/healthz", func(c *gin.Context) {
+		c.String(http.StatusOK, "ok")
+	})
+	group.GET("/readyz", s.readiness)
+	group.GET("/buildinfo", func(c *gin.Context) {
+		c.JSON(http.StatusOK, env.BuildInfo())
+	})
+	group.GET("/components", func(c *gin.Context) {
+		c.JSON(http.StatusOK, ecomponent.List())
+	})
+	group.GET("/config", func(c *gin.Context) {
+		settings := map[string]interface{}{}
+		if econfig.GlobalViper != nil {
+			settings = econfig.GlobalViper.AllSettings()
+		}
+		c.JSON(http.StatusOK, redact(settings, redactKeys))
+	})
+	group.GET("/debug/pprof/", gin.WrapF(pprof.Index))
+	group.GET("/debug/pprof/:name", pprofHandler)
+	group.POST("/debug/pprof/symbol", gin.WrapF(pprof.Symbol))
+	return nil
+}
+
+// pprofHandler pprof.Index 只处理 /debug/pprof/ 前缀, 带路由前缀时按名称分发
+func pprofHandler(c *gin.Context) {
+	switch name := c.Param("name"); name {
+	case "cmdline":
+		pprof.Cmdline(c.Writer, c.Request)
+	case "profile":
+		pprof.Profile(c.Writer, c.Request)
+	case "symbol":
+		pprof.Symbol(c.Writer, c.Request)
+	case "trace":
+		pprof.Trace(c.Writer, c.Request)
+	default:
+		pprof.Handler(name).ServeHTTP(c.Writer, c.Request)
+	}
+}
+
+// adminAuth AllowIPs 中的 IP 或 token 正确时允许访问, 都没有配置时只允许本机访问, 只用于单独端口
+// 使用连接的地址, 不信任 X-Forwarded-For
+func adminAuth(conf http_server_config.AdminConfig) (gin.HandlerFunc, error) {
+	var allows []*net.IPNet
+	for _, allow := range conf.AllowIPs {
+		if !strings.Contains(allow, "/") {
+			if ip := net.ParseIP(allow); ip != nil && ip.To4() != nil {
+				allow += "/32"
+			} else {
+				allow += "/128"
+			}
+		}
+		_, ipNet, err := net.ParseCIDR(allow)
+		if err != nil {
+			return nil, fmt.Errorf("invalid admin allow ip %s: %w", allow, err)
+		}
+		allows = append(allows, ipNet)
+	}
+	localOnly := len(allows) == 0 && conf.Token == ""
+
+	return func(c *gin.Context) {
+		ip := net.ParseIP(c.RemoteIP())
+		if localOnly && ip != nil && ip.IsLoopback() {
+			return
+		}
+		for _, ipNet := range allows {
+			if ip != nil && ipNet.Contains(ip) {
+				return
+			}
+		}
+		if conf.Token != "" {
+			token := c.GetHeader(AdminTokenHeader)
+			if token == "" {
+				token = strings.TrimPrefix(c.GetHeader("Authorization"), "Bearer ")
+			}
+			if subtle.ConstantTimeCompare([]byte(token), []byte(conf.Token)) == 1 {
+				return
+			}
+		}
+		c.AbortWithStatus(http.StatusForbidden)
+	}, nil
+}
+
+// redact key 包含关键字的配置替换为 ******, 包括整个子配置
+func redact(value interface{}, keys []string) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			if sensitive(k, keys) {
+				result[k] = redacted
+			} else {
+				result[k] = redact(item, keys)
+			}
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, item := range v {
+			result[i] = redact(item, keys)
+		}
+		return result
+	}
+	return value
+}
+
+func sensitive(key string, keys []string) bool {
+	key = strings.ToLower(key)
+	for _, k := range keys {
+		if strings.Contains(key, strings.ToLower(k)) {
+			return true
+		}
+	}
+	return false
+}
+
+// startAdmin 管理接口使用单独端口时开始监听
+func (s *HttpServer) startAdmin() error {
+	if s.adminServer == nil {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.adminServer.Addr)
+	if err != nil {
+		elog.ErrorCtx(emptyCtx, "admin listen err", elog.FieldError(err))
+		return err
+	}
+	go s.serveAdmin(listener)
+	return nil
+}
+
+// startAdminRetry endless 热重启时父进程退出前子进程无法监听管理端口, 在后台重试直到监听成功或 done 关闭
+func (s *HttpServer) startAdminRetry(done <-chan struct{}) {
+	if s.adminServer == nil {
+		return
+	}
+	go func() {
+		for {
+			listener, err := net.Listen("tcp", s.adminServer.Addr)
+			if err == nil {
+				s.serveAdmin(listener)
+				return
+			}
+			elog.WarnCtx(emptyCtx, "admin listen err, retrying", elog.FieldError(err))
+			select {
+			case <-done:
+				return
+			case <-time.After(adminRetryInterval):
+			}
+		}
+	}()
+}
+
+// serveAdmin Shutdown 之后调用时立即返回
+func (s *HttpServer) serveAdmin(listener net.Listener) {
+	if err := s.adminServer.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		elog.ErrorCtx(emptyCtx, "admin serve err", elog.FieldError(err))
+	}
+}
+
+// stopAdmin 在业务端口之后关闭, 关闭期间仍然可以查看监控
+func (s *HttpServer) stopAdmin(ctx context.Context) {
+	if s.adminServer == nil {
+		return
+	}
+	if err := s.adminServer.Shutdown(ctx); err != nil {
+		_ = s.adminServer.Close()
+	}
+}
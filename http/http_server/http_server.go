@@ -2,20 +2,43 @@ package http_server
 
 import (
 	"context"
-	"fmt"
+	"errors"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"sync/atomic"
+	"syscall"
+	"time"
 
 	"github.com/fvbock/endless"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/viper"
 	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/econfig/nacos"
+	"github.com/weblazy/easy/elog"
 	"github.com/weblazy/easy/http/http_server/http_server_config"
 	"github.com/weblazy/easy/http/http_server/interceptor"
+	"github.com/weblazy/easy/http/http_server/openapi"
 )
 
+var emptyCtx = context.Background()
+
 type HttpServer struct {
 	Config *http_server_config.Config
 	*gin.Engine
+	OpenAPI *openapi.Spec // 路由的接口描述, 开启 OpenAPIPath 时生成文档
+
+	registrar *nacos.Registrar
+	drainOnce sync.Once
+	drainErr  error
+
+	server      *http.Server
+	listener    net.Listener
+	ready       atomic.Bool
+	adminServer *http.Server // 管理接口使用单独端口时的 server
 }
 
 func NewHttpServerViper(key string, cfg *viper.Viper) (*HttpServer, error) {
@@ -33,7 +56,8 @@ func NewHttpServer(c *http_server_config.Config) (*HttpServer, error) {
 	}
 
 	server := &HttpServer{
-		Config: c,
+		Config:  c,
+		OpenAPI: openapi.New(c.Name, "1.0.0"),
 	}
 	ctx := context.Background()
 	// opts = append([]RunOption{WithNotFoundHandler(nil)}, opts...)
@@ -41,6 +65,18 @@ func NewHttpServer(c *http_server_config.Config) (*HttpServer, error) {
 	// 	opt(server)
 	// }
 	r := gin.New()
+	// 就绪检查在中间件之前注册, 不记录日志和监控
+	if c.ReadinessPath != "" {
+		r.GET(c.ReadinessPath, server.readiness)
+		server.OpenAPI.Ignore(c.ReadinessPath)
+	}
+	if c.OpenAPIPath != "" {
+		r.GET(c.OpenAPIPath, server.OpenAPI.Handler(r))
+		server.OpenAPI.Ignore(c.OpenAPIPath)
+	}
+	if err := server.initAdmin(r); err != nil {
+		return nil, err
+	}
 	r.Use(interceptor.SetStartTimeInterceptor())
 	if server.Config.EnableTraceInterceptor {
 		r.Use(otelgin.Middleware(c.Name))
@@ -54,14 +90,166 @@ func NewHttpServer(c *http_server_config.Config) (*HttpServer, error) {
 	if server.Config.EnableMetricInterceptor {
 		r.Use(interceptor.MetricInterceptor(c))
 	}
-	if server.Config.Timeout > 0 {
-		r.Use(interceptor.Timeout(server.Config.Timeout))
+	if server.Config.Timeout > 0 || len(server.Config.RouteTimeouts) > 0 {
+		timeoutConf := &interceptor.TimeoutConf{Timeout: server.Config.Timeout, Routes: make(map[string]time.Duration)}
+		for _, rt := range server.Config.RouteTimeouts {
+			timeoutConf.Routes[rt.Path] = rt.Timeout
+		}
+		r.Use(interceptor.TimeoutWithConf(timeoutConf))
 	}
 	r.Use(gin.Recovery())
 	server.Engine = r
 	return server, nil
 }
 
+// Init 初始化, standard 模式创建 listener, 端口为 0 时使用随机端口
+// endless 模式由 endless 创建 listener, 不需要调用
+func (s *HttpServer) Init() error {
+	if s.Config.Mode == http_server_config.ModeEndless {
+		return nil
+	}
+	listener, err := net.Listen("tcp", s.Config.Address())
+	if err != nil {
+		elog.ErrorCtx(emptyCtx, "new http server err", elog.FieldError(err))
+		return err
+	}
+	s.Config.Port = listener.Addr().(*net.TCPAddr).Port
+	s.listener = listener
+	s.server = &http.Server{Handler: s}
+	s.initRegistrar()
+	return nil
+}
+
+// Start 启动服务, 阻塞直到服务关闭, 开启服务注册时在监听成功后注册到 nacos
 func (s *HttpServer) Start() error {
-	return endless.ListenAndServe(fmt.Sprintf("%s:%d", s.Config.Host, s.Config.Port), s)
+	if s.Config.Mode == http_server_config.ModeEndless {
+		return s.startEndless()
+	}
+	if s.listener == nil {
+		if err := s.Init(); err != nil {
+			return err
+		}
+	}
+	if err := s.startAdmin(); err != nil {
+		return err
+	}
+	// 开启服务注册时, 注册失败不启动服务
+	if err := s.register(); err != nil {
+		s.stopAdmin(emptyCtx)
+		return err
+	}
+	closes.AddShutdown(closes.ModuleClose{
+		Name:     "http_server graceful stop",
+		Priority: closes.ServerPriority,
+		Func: func() {
+			ctx, cancel := context.WithTimeout(emptyCtx, s.Config.ShutdownTimeout)
+			defer cancel()
+			_ = s.GracefulStop(ctx)
+		},
+	})
+	s.ready.Store(true)
+	err := s.server.Serve(s.listener)
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// startEndless 使用 endless 启动
+// 收到 SIGINT/SIGTERM 时先注销实例再关闭 listener, SIGHUP 热重启时由子进程重新注册
+func (s *HttpServer) startEndless() error {
+	server := endless.NewServer(s.Config.Address(), s)
+	s.server = &server.Server
+	s.initRegistrar()
+	adminDone := make(chan struct{})
+	s.startAdminRetry(adminDone)
+	defer s.stopAdmin(emptyCtx)
+	defer close(adminDone)
+	beforeBegin := server.BeforeBegin
+	var registerErr error
+	server.BeforeBegin = func(addr string) {
+		beforeBegin(addr)
+		// 与 standard 模式一致, 注册失败不启动服务
+		if registerErr = s.register(); registerErr != nil {
+			elog.ErrorCtx(emptyCtx, "http register err", elog.FieldError(registerErr))
+			_ = server.Server.Close()
+			return
+		}
+		s.ready.Store(true)
+	}
+	var forked atomic.Bool
+	_ = server.RegisterSignalHook(endless.PRE_SIGNAL, syscall.SIGHUP, func() {
+		forked.Store(true)
+	})
+	for _, sig := range []os.Signal{syscall.SIGINT, syscall.SIGTERM} {
+		_ = server.RegisterSignalHook(endless.PRE_SIGNAL, sig, func() {
+			if !forked.Load() {
+				_ = s.drain(emptyCtx)
+			}
+		})
+	}
+	err := server.ListenAndServe()
+	if registerErr != nil {
+		return registerErr
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		return nil
+	}
+	return err
+}
+
+// Stop 立即关闭服务, 不等待处理中的请求
+func (s *HttpServer) Stop() error {
+	s.ready.Store(false)
+	s.deregister()
+	if s.adminServer != nil {
+		_ = s.adminServer.Close()
+	}
+	if s.server == nil {
+		return nil
+	}
+	return s.server.Close()
+}
+
+// GracefulStop 优雅关闭
+// 先从 nacos 注销, 就绪检查返回 503, 等待 DrainDelay 让上游摘除流量后再等待处理中的请求结束
+// DrainDelay 最多等待到 ctx 结束, 之后总会关闭 listener, 超时时强制关闭连接
+func (s *HttpServer) GracefulStop(ctx context.Context) error {
+	defer s.stopAdmin(ctx)
+	drainErr := s.drain(ctx)
+	if drainErr != nil {
+		elog.WarnCtx(ctx, "http drain timeout", elog.FieldError(drainErr))
+	}
+	if s.server == nil {
+		return drainErr
+	}
+	if err := s.server.Shutdown(ctx); err != nil {
+		elog.WarnCtx(ctx, "http graceful shutdown timeout", elog.FieldError(err))
+		_ = s.server.Close()
+		return err
+	}
+	if drainErr != nil {
+		return drainErr
+	}
+	elog.InfoCtx(ctx, "http graceful shutdown success")
+	return nil
+}
+
+// Ready 是否就绪, Start 之后为 true, 开始关闭后为 false
+func (s *HttpServer) Ready() bool {
+	return s.ready.Load()
+}
+
+// readiness 就绪检查, 未就绪时返回 503
+func (s *HttpServer) readiness(c *gin.Context) {
+	if !s.Ready() {
+		c.String(http.StatusServiceUnavailable, "not ready")
+		return
+	}
+	c.String(http.StatusOK, "ok")
+}
+
+// Listener 监听的 listener, standard 模式 Init 之后可用
+func (s *HttpServer) Listener() net.Listener {
+	return s.listener
 }
@@ -1,31 +1,72 @@
 package http_server_config
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/spf13/viper"
+	"github.com/weblazy/easy/econfig/nacos"
 	"github.com/weblazy/easy/elog"
 	"github.com/weblazy/easy/elog/ezap"
 )
 
 const PkgName = "http_server"
 
+// UnmatchedPath 没有匹配路由的请求 (404) 统一使用的指标 path, 防止 metrics label 不可控
+const UnmatchedPath = "unmatched"
+
+// 启动方式
+const (
+	ModeEndless  = "endless"  // 使用 endless 启动, 支持 SIGHUP 热重启
+	ModeStandard = "standard" // 使用 net/http.Server 启动, 适合容器环境, 由 closes 或调用方优雅关闭
+)
+
 type Config struct {
 	Name string
 	Host string // IP地址，默认0.0.0.0
 	Port int    // Port端口，默认80
 
-	Timeout          time.Duration
-	SlowLogThreshold time.Duration // 慢日志记录的阈值，默认 1s
+	Timeout          time.Duration  // 请求处理超时时间, 超时返回 504 和 code_err.TimeoutErr, 0 为不限制
+	RouteTimeouts    []RouteTimeout // 单个路由的超时时间, 优先于 Timeout
+	SlowLogThreshold time.Duration  // 慢日志记录的阈值，默认 1s
 
 	EnableTraceInterceptor  bool
 	EnableMetricInterceptor bool
 	EnableLogInterceptor    bool
 	EnableAccessInterceptor bool // 是否开启记录请求数据，默认开启
 
-	EnableFielLogger   bool // 将日志输出到文件
-	FielLoggerPath     string
-	MetricPathRewriter MetricPathRewriter
+	EnableFielLogger    bool // 将日志输出到文件
+	FielLoggerPath      string
+	MetricPathRewriter  MetricPathRewriter // 指标监控 path 重写方法, 参数为路由模板, 如 /user/:id
+	MetricPathAllowlist []string           // 使用原始 path 作为指标 label 的路径, 如 NoRoute 处理的固定路径, 默认为空
+
+	EnableRegistry bool                  // 是否注册到 nacos 服务发现, 默认关闭
+	Registry       nacos.RegistrarConfig // 服务注册配置, 服务名默认为 Name, 端口默认为 Port
+	DrainDelay     time.Duration         // 关闭时从 nacos 注销, 就绪检查失败后等待流量摘除的时间, 建议大于 k8s readinessProbe 的 periodSeconds, 默认 0
+
+	Mode            string        // 启动方式, endless 或 standard, 默认 endless
+	ReadinessPath   string        // 就绪检查路径, 启动后返回 200, 开始关闭后返回 503, 为空时不注册, 如 /readyz, 默认为空
+	ShutdownTimeout time.Duration // standard 模式程序退出时优雅关闭的超时时间, 默认 10s
+
+	OpenAPIPath string // OpenAPI 文档路径, 如 /openapi.json, 为空时不注册, 默认为空
+
+	Admin AdminConfig // 管理接口, 默认关闭
+}
+
+// RouteTimeout 路由的超时时间, Timeout 为 0 时不限制, 用于文件下载等需要 Flush 的路由, websocket 和 SSE 请求不受超时限制
+type RouteTimeout struct {
+	Path    string // 路由模板, 如 /v1/user/:id
+	Timeout time.Duration
+}
+
+// AdminConfig 管理接口配置, 提供 metrics, pprof, healthz, readyz, 编译信息和脱敏后的配置
+type AdminConfig struct {
+	Enable     bool
+	Port       int      // 独立监听的端口, 为 0 时注册到业务端口
+	PathPrefix string   // 路由前缀, 注册到业务端口时默认 /admin
+	AllowIPs   []string // 允许访问的 IP 或 CIDR, 和 Token 都为空时只允许本机访问, 注册到业务端口时至少需要配置其中一个
+	Token      string   // 不在 AllowIPs 中时需要 X-Admin-Token 或 Authorization: Bearer <token>
+	RedactKeys []string // 配置中需要脱敏的 key 包含的关键字, 不区分大小写, 默认 password, secret, token, key 等
 }
 
 // DefaultConfig default config ...
@@ -41,15 +82,23 @@ func DefaultConfig() *Config {
 		EnableAccessInterceptor: true,
 		FielLoggerPath:          PkgName,
 		MetricPathRewriter:      DefaultMetricPathRewriter,
+		Mode:                    ModeEndless,
+		ShutdownTimeout:         10 * time.Second,
 	}
 }
 
+// MetricPathRewriter 重写指标 path, origin 为路由模板或 MetricPathAllowlist 中的原始 path
 type MetricPathRewriter func(origin string) string
 
 func DefaultMetricPathRewriter(origin string) string {
 	return origin
 }
 
+// Address 监听地址
+func (config *Config) Address() string {
+	return fmt.Sprintf("%s:%d", config.Host, config.Port)
+}
+
 func GetViperConfig(key string, cfg *viper.Viper) (*Config, error) {
 	c := DefaultConfig()
 	err := cfg.UnmarshalKey(key, c)
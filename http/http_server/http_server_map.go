@@ -0,0 +1,44 @@
+package http_server
+
+import (
+	"sync"
+
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/http/http_server/http_server_config"
+)
+
+var (
+	HttpServerMap sync.Map
+	httpServerMu  sync.Mutex
+)
+
+// GetHttpServer 按照配置 key 获取 http 服务, 不存在时从 econfig.GlobalViper 读取配置创建
+// Start 时会注册优雅关闭, 这里只在程序退出时删除缓存
+func GetHttpServer(name string) (*HttpServer, error) {
+	if v, ok := HttpServerMap.Load(name); ok {
+		return v.(*HttpServer), nil
+	}
+	httpServerMu.Lock()
+	defer httpServerMu.Unlock()
+	if v, ok := HttpServerMap.Load(name); ok {
+		return v.(*HttpServer), nil
+	}
+
+	conf := http_server_config.DefaultConfig()
+	econfig.GlobalViper.UnmarshalKey(name, conf)
+	if conf.Name == "" {
+		conf.Name = name
+	}
+	server, err := NewHttpServer(conf)
+	if err != nil {
+		return nil, err
+	}
+
+	HttpServerMap.Store(name, server)
+	ecomponent.Register(ecomponent.KindHttpServer, name, closes.ServerPriority, func() {
+		HttpServerMap.Delete(name)
+	})
+	return server, nil
+}
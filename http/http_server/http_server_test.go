@@ -1,9 +1,27 @@
 package http_server
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus/testutil"
 	"github.com/smartystreets/goconvey/convey"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/ecomponent"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/econfig/eviper"
+	"github.com/weblazy/easy/http/http_server/http_server_config"
+	"github.com/weblazy/easy/http/http_server/interceptor"
 )
 
 func TestNewHttpServer(t *testing.T) {
@@ -18,6 +36,357 @@ func TestNewHttpServer(t *testing.T) {
 	})
 }
 
+func TestGetHttpServer(t *testing.T) {
+	econfig.GlobalViper = eviper.NewViperFromString(`
+[userHttp]
+Port = 8081
+EnableTraceInterceptor = false
+`)
+	server, err := GetHttpServer("userHttp")
+	assert.Nil(t, err)
+	assert.Equal(t, "userHttp", server.Config.Name)
+	assert.Equal(t, 8081, server.Config.Port)
+	again, err := GetHttpServer("userHttp")
+	assert.Nil(t, err)
+	assert.Equal(t, server, again)
+
+	var found bool
+	for _, c := range ecomponent.List() {
+		if c.Kind == ecomponent.KindHttpServer && c.Name == "userHttp" {
+			found = true
+		}
+	}
+	assert.True(t, found)
+}
+
+func newStandardServer(t *testing.T) (*HttpServer, string) {
+	cfg := http_server_config.DefaultConfig()
+	cfg.Mode = http_server_config.ModeStandard
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 0
+	cfg.DrainDelay = 100 * time.Millisecond
+	cfg.ReadinessPath = "/readyz"
+	cfg.EnableTraceInterceptor = false
+	server, err := NewHttpServer(cfg)
+	assert.Nil(t, err)
+	assert.Nil(t, server.Init())
+	return server, fmt.Sprintf("http://127.0.0.1:%d", cfg.Port)
+}
+
+func get(url string) (int, string) {
+	client := http.Client{Timeout: time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err.Error()
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(body)
+}
+
+func TestHttpServer_GracefulStop(t *testing.T) {
+	server, addr := newStandardServer(t)
+	server.GET("/slow", func(c *gin.Context) {
+		time.Sleep(200 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+
+	assert.False(t, server.Ready())
+	done := make(chan error)
+	go func() {
+		done <- server.Start()
+	}()
+	assert.Eventually(t, server.Ready, time.Second, 10*time.Millisecond)
+	code, _ := get(addr + "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+
+	slow := make(chan string)
+	go func() {
+		_, body := get(addr + "/slow")
+		slow <- body
+	}()
+	time.Sleep(20 * time.Millisecond)
+
+	stopped := make(chan error)
+	go func() {
+		stopped <- server.GracefulStop(context.Background())
+	}()
+	// 等待流量摘除期间就绪检查失败, 仍然处理请求
+	assert.Eventually(t, func() bool {
+		code, _ := get(addr + "/readyz")
+		return code == http.StatusServiceUnavailable
+	}, time.Second, 10*time.Millisecond)
+
+	assert.Equal(t, "done", <-slow)
+	assert.Nil(t, <-stopped)
+	assert.Nil(t, <-done)
+	code, _ = get(addr + "/readyz")
+	assert.Equal(t, 0, code)
+}
+
+func TestHttpServer_ReadinessPathDefault(t *testing.T) {
+	cfg := http_server_config.DefaultConfig()
+	cfg.EnableTraceInterceptor = false
+	server, err := NewHttpServer(cfg)
+	assert.Nil(t, err)
+	// 默认不注册就绪检查, 不会和业务路由冲突
+	assert.NotPanics(t, func() {
+		server.GET("/readyz", func(c *gin.Context) {})
+	})
+}
+
+func TestHttpServer_GracefulStopTimeout(t *testing.T) {
+	server, addr := newStandardServer(t)
+	server.Config.DrainDelay = 0
+	server.GET("/slow", func(c *gin.Context) {
+		time.Sleep(300 * time.Millisecond)
+		c.String(http.StatusOK, "done")
+	})
+	go func() {
+		_ = server.Start()
+	}()
+	assert.Eventually(t, server.Ready, time.Second, 10*time.Millisecond)
+	go get(addr + "/slow")
+	time.Sleep(20 * time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, server.GracefulStop(ctx))
+	assert.Nil(t, server.Stop())
+}
+
+func TestHttpServer_GracefulStopDrainTimeout(t *testing.T) {
+	server, addr := newStandardServer(t)
+	server.Config.DrainDelay = time.Minute
+	done := make(chan error)
+	go func() {
+		done <- server.Start()
+	}()
+	assert.Eventually(t, server.Ready, time.Second, 10*time.Millisecond)
+
+	// DrainDelay 超过 ctx 时仍然关闭 listener
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, server.GracefulStop(ctx))
+	select {
+	case err := <-done:
+		assert.Nil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("server not stopped")
+	}
+	code, _ := get(addr + "/readyz")
+	assert.Equal(t, 0, code)
+}
+
+func TestHttpServer_Hijack(t *testing.T) {
+	// 默认配置开启了超时, websocket 等协议升级请求仍然可以 Hijack
+	server, addr := newStandardServer(t)
+	server.GET("/ws", func(c *gin.Context) {
+		conn, rw, err := c.Writer.Hijack()
+		if !assert.Nil(t, err) {
+			return
+		}
+		defer conn.Close()
+		_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n")
+		_ = rw.Flush()
+		line, _ := rw.ReadString('\n')
+		_, _ = rw.WriteString("echo " + line)
+		_ = rw.Flush()
+	})
+	go func() {
+		_ = server.Start()
+	}()
+	defer server.Stop()
+	assert.Eventually(t, server.Ready, time.Second, 10*time.Millisecond)
+
+	conn, err := net.Dial("tcp", strings.TrimPrefix(addr, "http://"))
+	assert.Nil(t, err)
+	defer conn.Close()
+	_ = conn.SetDeadline(time.Now().Add(time.Second))
+	_, err = conn.Write([]byte("GET /ws HTTP/1.1\r\nHost: localhost\r\nConnection: Upgrade\r\nUpgrade: websocket\r\n\r\n"))
+	assert.Nil(t, err)
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, nil)
+	assert.Nil(t, err)
+	assert.Equal(t, http.StatusSwitchingProtocols, resp.StatusCode)
+	_, err = conn.Write([]byte("hello\n"))
+	assert.Nil(t, err)
+	line, err := br.ReadString('\n')
+	assert.Nil(t, err)
+	assert.Equal(t, "echo hello\n", line)
+}
+
+func TestHttpServer_EndlessRegisterErr(t *testing.T) {
+	cfg := http_server_config.DefaultConfig()
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 0
+	cfg.EnableRegistry = true
+	server, err := NewHttpServer(cfg)
+	assert.Nil(t, err)
+
+	// 没有初始化 nacos 服务发现客户端, 注册失败时不启动服务
+	done := make(chan error)
+	go func() {
+		done <- server.Start()
+	}()
+	select {
+	case err := <-done:
+		assert.NotNil(t, err)
+	case <-time.After(time.Second):
+		t.Fatal("endless server should stop when register failed")
+	}
+	assert.False(t, server.Ready())
+}
+
+func TestMetricRoutePath(t *testing.T) {
+	cfg := http_server_config.DefaultConfig()
+	cfg.Name = "route_path_test"
+	cfg.EnableTraceInterceptor = false
+	cfg.EnableLogInterceptor = false
+	cfg.MetricPathAllowlist = []string{"/favicon.ico"}
+	server, err := NewHttpServer(cfg)
+	assert.Nil(t, err)
+	server.GET("/user/:id", func(c *gin.Context) {
+		c.String(http.StatusOK, c.Param("id"))
+	})
+
+	for _, path := range []string{"/user/1", "/user/2", "/not/found/1", "/not/found/2", "/favicon.ico"} {
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	count := func(path, code string) float64 {
+		return testutil.ToFloat64(interceptor.ServerHandleCounter.WithLabelValues(cfg.Name, http.MethodGet, path, "", code))
+	}
+	assert.Equal(t, float64(2), count("/user/:id", "200"))
+	assert.Equal(t, float64(2), count(http_server_config.UnmatchedPath, "404"))
+	assert.Equal(t, float64(1), count("/favicon.ico", "404"))
+	assert.Equal(t, float64(0), count("/user/1", "200"))
+}
+
+func TestHttpServer_Admin(t *testing.T) {
+	econfig.GlobalViper = eviper.NewViperFromString(`
+[Mysql]
+Dsn = "root:123456@tcp(127.0.0.1:3306)/test"
+Host = "127.0.0.1"
+[[Apps]]
+Name = "app"
+Secret = "s1"
+`)
+	cfg := http_server_config.DefaultConfig()
+	cfg.EnableTraceInterceptor = false
+	cfg.OpenAPIPath = "/openapi.json"
+	cfg.Admin = http_server_config.AdminConfig{Enable: true, AllowIPs: []string{"10.0.0.0/8"}, Token: "t1"}
+	server, err := NewHttpServer(cfg)
+	assert.Nil(t, err)
+
+	request := func(path, remoteAddr, token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = remoteAddr
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+
+	// IP 白名单或 token
+	assert.Equal(t, http.StatusOK, request("/admin/healthz", "10.1.2.3:1234", "").Code)
+	assert.Equal(t, http.StatusForbidden, request("/admin/healthz", "192.168.1.1:1234", "").Code)
+	assert.Equal(t, http.StatusForbidden, request("/admin/healthz", "192.168.1.1:1234", "t2").Code)
+	assert.Equal(t, http.StatusOK, request("/admin/healthz", "192.168.1.1:1234", "t1").Code)
+
+	assert.Equal(t, http.StatusServiceUnavailable, request("/admin/readyz", "10.1.2.3:1234", "").Code)
+	assert.Contains(t, request("/admin/metrics", "10.1.2.3:1234", "").Body.String(), "go_goroutines")
+	assert.Equal(t, http.StatusOK, request("/admin/debug/pprof/", "10.1.2.3:1234", "").Code)
+	assert.Contains(t, request("/admin/debug/pprof/goroutine?debug=1", "10.1.2.3:1234", "").Body.String(), "goroutine profile")
+	assert.Contains(t, request("/admin/buildinfo", "10.1.2.3:1234", "").Body.String(), "go_version")
+
+	// 配置脱敏
+	settings := map[string]interface{}{}
+	assert.Nil(t, json.Unmarshal(request("/admin/config", "10.1.2.3:1234", "").Body.Bytes(), &settings))
+	assert.Equal(t, map[string]interface{}{"dsn": "******", "host": "127.0.0.1"}, settings["mysql"])
+	assert.Equal(t, []interface{}{map[string]interface{}{"Name": "app", "Secret": "******"}}, settings["apps"])
+
+	// 管理接口不在 OpenAPI 文档中
+	assert.NotContains(t, request("/openapi.json", "10.1.2.3:1234", "").Body.String(), "/admin")
+}
+
+func TestHttpServer_AdminPort(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+	assert.Nil(t, l.Close())
+
+	cfg := http_server_config.DefaultConfig()
+	cfg.Mode = http_server_config.ModeStandard
+	cfg.Host = "127.0.0.1"
+	cfg.Port = 0
+	cfg.EnableTraceInterceptor = false
+	cfg.Admin = http_server_config.AdminConfig{Enable: true, Port: port}
+	server, err := NewHttpServer(cfg)
+	assert.Nil(t, err)
+	done := make(chan error)
+	go func() {
+		done <- server.Start()
+	}()
+	assert.Eventually(t, server.Ready, time.Second, 10*time.Millisecond)
+
+	// 默认只允许本机访问, 管理接口不在业务端口
+	admin := fmt.Sprintf("http://127.0.0.1:%d", port)
+	code, body := get(admin + "/readyz")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, "ok", body)
+	code, _ = get(fmt.Sprintf("http://127.0.0.1:%d/admin/healthz", cfg.Port))
+	assert.Equal(t, http.StatusNotFound, code)
+
+	assert.Nil(t, server.GracefulStop(context.Background()))
+	assert.Nil(t, <-done)
+	code, _ = get(admin + "/healthz")
+	assert.Equal(t, 0, code)
+}
+
+func TestHttpServer_AdminRequiresAuth(t *testing.T) {
+	// 注册到业务端口时必须配置 AllowIPs 或 Token
+	cfg := http_server_config.DefaultConfig()
+	cfg.EnableTraceInterceptor = false
+	cfg.Admin = http_server_config.AdminConfig{Enable: true}
+	_, err := NewHttpServer(cfg)
+	assert.NotNil(t, err)
+
+	cfg.Admin.Port = 9999
+	_, err = NewHttpServer(cfg)
+	assert.Nil(t, err)
+}
+
+func TestHttpServer_AdminRetry(t *testing.T) {
+	adminRetryInterval = 10 * time.Millisecond
+	defer func() {
+		adminRetryInterval = time.Second
+	}()
+	// 模拟热重启时父进程还在监听管理端口
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	port := l.Addr().(*net.TCPAddr).Port
+
+	cfg := http_server_config.DefaultConfig()
+	cfg.Host = "127.0.0.1"
+	cfg.EnableTraceInterceptor = false
+	cfg.Admin = http_server_config.AdminConfig{Enable: true, Port: port}
+	server, err := NewHttpServer(cfg)
+	assert.Nil(t, err)
+	done := make(chan struct{})
+	defer close(done)
+	server.startAdminRetry(done)
+	defer server.stopAdmin(context.Background())
+
+	time.Sleep(30 * time.Millisecond)
+	assert.Nil(t, l.Close())
+	assert.Eventually(t, func() bool {
+		code, _ := get(fmt.Sprintf("http://127.0.0.1:%d/healthz", port))
+		return code == http.StatusOK
+	}, time.Second, 10*time.Millisecond)
+}
+
 // func TestNewHttpServerViper(t *testing.T) {
 // 	convey.Convey("test config", t, func() {
 // 		cfg := viper.New()
@@ -9,6 +9,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net/http"
+	"net/url"
 	"runtime"
 
 	"io/ioutil"
@@ -19,6 +20,7 @@ import (
 	"github.com/weblazy/easy/code_err"
 	"github.com/weblazy/easy/elog"
 	"github.com/weblazy/easy/env"
+	"github.com/weblazy/easy/http/http_server/service"
 	"go.uber.org/zap"
 )
 
@@ -30,21 +32,73 @@ const (
 	NonceHeader     = "X-Nonce"
 	TimestampHeader = "X-Timestamp"
 	SignHeader      = "X-Sign"
-	LanguageHeader  = "X-Language"
+	LanguageHeader  = service.LanguageHeader
 	TokenPrefix     = "token#"
 	UserPrefix      = "user#"
 	CodeExpiryTime  = 15 * 60                 //15分钟
 	TokenExpiryTime = 7 * 86400 * time.Second //7天
 )
 
-// Auth
-func Auth(c *gin.Context) {
-	if c.Request.Method == http.MethodGet {
+// EncryptQueryParam GET 请求加密后的 query 参数名, 值为整个 query string 加密后的内容
+const EncryptQueryParam = "data"
+
+// defaultMultipartMemory 解析 multipart 表单使用的内存, 与 gin 默认值一致
+const defaultMultipartMemory = 32 << 20
+
+// AuthConf 请求解密配置
+type AuthConf struct {
+	DecryptQuery     bool // 解密 GET 请求的 query 参数 data, 默认关闭, GET 请求为明文
+	DecryptMultipart bool // 解密 multipart 请求的每个表单字段, 默认关闭, 表单为明文
+	EncryptResponse  bool // 使用与请求相同的密钥加密 service.ServiceContext 的响应
+}
 
-	} else if c.ContentType() == gin.MIMEJSON {
-		AuthJson(c)
-	} else if c.ContentType() == gin.MIMEMultipartPOSTForm {
+var defaultAuth = AuthWithConf(&AuthConf{})
+
+// Auth 解密 json 请求, GET 和 multipart 表单为明文, 不加密响应
+func Auth(c *gin.Context) {
+	defaultAuth(c)
+}
 
+// AuthWithConf 解密请求, 密钥为 X-Token 的 sha256, 没有 token 时使用 X-Nonce+X-Timestamp
+// json 请求解密整个 body, 开启后 GET 请求解密 query 参数 data, multipart 请求解密每个表单字段, 文件不加密
+// 开启 EncryptResponse 后 service.ServiceContext 的响应使用相同的密钥加密, 非线上环境 X-Debug: test 时不解密也不加密
+func AuthWithConf(conf *AuthConf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		defer func() {
+			if err := recover(); err != nil {
+				GetCurrentGoroutineStack(err)
+				Error(c, code_err.ParamsErr, fmt.Errorf("panic"))
+			}
+		}()
+		if skipDecrypt(c.Request.Header) {
+			c.Next()
+			return
+		}
+		key := EncryptKey(c.Request.Header)
+		var err error
+		switch {
+		case c.Request.Method == http.MethodGet:
+			if conf.DecryptQuery {
+				err = decryptQuery(c.Request, key)
+			}
+		case c.ContentType() == gin.MIMEJSON:
+			err = decryptBody(c.Request, key)
+		case c.ContentType() == gin.MIMEMultipartPOSTForm:
+			if conf.DecryptMultipart {
+				err = decryptMultipart(c.Request, key)
+			}
+		default:
+			c.Next()
+			return
+		}
+		if err != nil {
+			Error(c, code_err.DecryptErr, err)
+			return
+		}
+		if conf.EncryptResponse {
+			c.Set(service.EncryptKey, key)
+		}
+		c.Next()
 	}
 }
 
@@ -55,36 +109,93 @@ func AuthJson(c *gin.Context) {
 			Error(c, code_err.ParamsErr, fmt.Errorf("panic"))
 		}
 	}()
-	req := c.Request
-	header := req.Header
-	debugKey := header.Get(DebugHeader)
-	var bodyBytes []byte
-	bodyBytes, err := ioutil.ReadAll(c.Request.Body)
-	if err != nil {
-		Error(c, code_err.ParamsErr, fmt.Errorf("Invalid request body"))
-		return
-	}
-
-	if env.GetRunTime() == "onl" || debugKey != "test" {
-		token := header.Get(TokenHeader)
-		if token == "" {
-			timestamp := header.Get(TimestampHeader)
-			nonce := header.Get(NonceHeader)
-			token = nonce + timestamp
-		}
-		encryptKey := Sha256([]byte(token))
-		aesObj := aes.NewAes(encryptKey)
-		requestBody, err := aesObj.Decrypt(string(bodyBytes))
-		if err != nil {
+	if !skipDecrypt(c.Request.Header) {
+		if err := decryptBody(c.Request, EncryptKey(c.Request.Header)); err != nil {
 			Error(c, code_err.DecryptErr, err)
 			return
 		}
-		bodyBytes = []byte(requestBody)
 	}
+	c.Next()
+}
+
+// skipDecrypt 非线上环境 X-Debug: test 时请求为明文
+func skipDecrypt(header http.Header) bool {
+	return env.GetRunTime() != "onl" && header.Get(DebugHeader) == "test"
+}
 
+// EncryptKey 请求和响应的加密密钥, X-Token 的 sha256, 没有 token 时使用 X-Nonce+X-Timestamp
+func EncryptKey(header http.Header) []byte {
+	token := header.Get(TokenHeader)
+	if token == "" {
+		token = header.Get(NonceHeader) + header.Get(TimestampHeader)
+	}
+	return Sha256([]byte(token))
+}
+
+// decrypt aes 解密, 密钥错误时填充校验会 panic, 转换为 error
+func decrypt(key []byte, data string) (result string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("decrypt failed: %v", r)
+		}
+	}()
+	return aes.NewAes(key).Decrypt(data)
+}
+
+func decryptBody(req *http.Request, key []byte) error {
+	bodyBytes, err := ioutil.ReadAll(req.Body)
+	if err != nil {
+		return fmt.Errorf("Invalid request body")
+	}
+	requestBody, err := decrypt(key, string(bodyBytes))
+	if err != nil {
+		return err
+	}
 	// 新建缓冲区并替换原有Request.body
-	c.Request.Body = ioutil.NopCloser(bytes.NewBuffer([]byte(bodyBytes)))
-	c.Next()
+	req.Body = ioutil.NopCloser(bytes.NewBufferString(requestBody))
+	return nil
+}
+
+// decryptQuery 使用解密后的 query string 替换原有的 query, 没有 data 参数时不处理
+func decryptQuery(req *http.Request, key []byte) error {
+	data := req.URL.Query().Get(EncryptQueryParam)
+	if data == "" {
+		return nil
+	}
+	rawQuery, err := decrypt(key, data)
+	if err != nil {
+		return err
+	}
+	if _, err := url.ParseQuery(rawQuery); err != nil {
+		return err
+	}
+	req.URL.RawQuery = rawQuery
+	req.Form = nil
+	return nil
+}
+
+// decryptMultipart 解密表单字段并重新生成 Form 和 PostForm, gin 读取表单时不会再次解析
+func decryptMultipart(req *http.Request, key []byte) error {
+	if err := req.ParseMultipartForm(defaultMultipartMemory); err != nil {
+		return err
+	}
+	postForm := make(url.Values, len(req.MultipartForm.Value))
+	for k, values := range req.MultipartForm.Value {
+		for i, v := range values {
+			value, err := decrypt(key, v)
+			if err != nil {
+				return fmt.Errorf("decrypt form field %s: %w", k, err)
+			}
+			values[i] = value
+		}
+		postForm[k] = values
+	}
+	form := req.URL.Query()
+	for k, values := range postForm {
+		form[k] = append(form[k], values...)
+	}
+	req.PostForm, req.Form = postForm, form
+	return nil
 }
 
 func Sha256ToHex(text []byte) string {
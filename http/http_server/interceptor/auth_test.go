@@ -0,0 +1,163 @@
+package interceptor
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/crypto/aes"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/http/http_server/service"
+)
+
+func encrypt(t *testing.T, token, data string) string {
+	result, err := aes.NewAes(Sha256([]byte(token))).Encrypt(data)
+	assert.Nil(t, err)
+	return result
+}
+
+// decryptResponse 解密响应, 没有加密时直接解析
+func decryptResponse(t *testing.T, w *httptest.ResponseRecorder, token string) *service.Response {
+	body := w.Body.String()
+	if w.Header().Get(service.EncryptHeader) == "1" {
+		var err error
+		body, err = aes.NewAes(Sha256([]byte(token))).Decrypt(body)
+		assert.Nil(t, err)
+	}
+	resp := &service.Response{}
+	assert.Nil(t, json.Unmarshal([]byte(body), resp))
+	return resp
+}
+
+func newAuthEngine(conf *AuthConf) *gin.Engine {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(AuthWithConf(conf))
+	handler := func(c *gin.Context) {
+		ctx := service.NewServiceContext(c)
+		req := map[string]string{}
+		if c.ContentType() == gin.MIMEJSON {
+			if err := ctx.BindValidator(&req); err != nil {
+				ctx.Error(err)
+				return
+			}
+		} else {
+			req["name"] = c.Query("name") + c.PostForm("name")
+		}
+		ctx.Success(req)
+	}
+	r.GET("/auth", handler)
+	r.POST("/auth", handler)
+	return r
+}
+
+func TestAuthWithConf(t *testing.T) {
+	r := newAuthEngine(&AuthConf{DecryptQuery: true, DecryptMultipart: true, EncryptResponse: true})
+
+	// json
+	req := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(encrypt(t, "t1", `{"name":"json"}`)))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	req.Header.Set(TokenHeader, "t1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "1", w.Header().Get(service.EncryptHeader))
+	assert.Equal(t, map[string]interface{}{"name": "json"}, decryptResponse(t, w, "t1").Data)
+
+	// GET 没有 token 时使用 nonce+timestamp
+	query := url.Values{EncryptQueryParam: {encrypt(t, "n1"+"1700000000", "name=get")}}
+	req = httptest.NewRequest(http.MethodGet, "/auth?"+query.Encode(), nil)
+	req.Header.Set(NonceHeader, "n1")
+	req.Header.Set(TimestampHeader, "1700000000")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, map[string]interface{}{"name": "get"}, decryptResponse(t, w, "n11700000000").Data)
+
+	// multipart 解密表单字段
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	assert.Nil(t, mw.WriteField("name", encrypt(t, "t1", "form")))
+	assert.Nil(t, mw.Close())
+	req = httptest.NewRequest(http.MethodPost, "/auth", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set(TokenHeader, "t1")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, map[string]interface{}{"name": "form"}, decryptResponse(t, w, "t1").Data)
+
+	// 密钥错误
+	req = httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(encrypt(t, "t1", `{"name":"json"}`)))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	req.Header.Set(TokenHeader, "t2")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "", w.Header().Get(service.EncryptHeader))
+	assert.Equal(t, code_err.DecryptErr.Code, decryptResponse(t, w, "t2").Code)
+
+	// 错误信息按照请求的语言翻译
+	req = httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	req.Header.Set("Accept-Language", "zh-CN,zh;q=0.9")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "解密失败", decryptResponse(t, w, "").Msg)
+}
+
+func TestAuthWithConf_Plain(t *testing.T) {
+	// 没有开启响应加密
+	r := newAuthEngine(&AuthConf{})
+	req := httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(encrypt(t, "t1", `{"name":"json"}`)))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	req.Header.Set(TokenHeader, "t1")
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "", w.Header().Get(service.EncryptHeader))
+	assert.Equal(t, map[string]interface{}{"name": "json"}, decryptResponse(t, w, "").Data)
+
+	// 默认不解密 GET 和 multipart 表单
+	req = httptest.NewRequest(http.MethodGet, "/auth?name=get&"+EncryptQueryParam+"=plain", nil)
+	req.Header.Set(TokenHeader, "t1")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, map[string]interface{}{"name": "get"}, decryptResponse(t, w, "").Data)
+
+	body := &bytes.Buffer{}
+	mw := multipart.NewWriter(body)
+	assert.Nil(t, mw.WriteField("name", "form"))
+	assert.Nil(t, mw.Close())
+	req = httptest.NewRequest(http.MethodPost, "/auth", body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	req.Header.Set(TokenHeader, "t1")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, map[string]interface{}{"name": "form"}, decryptResponse(t, w, "").Data)
+
+	// 非线上环境 debug 时请求和响应都不加密
+	t.Setenv("RUN_TIME", "test")
+	r = newAuthEngine(&AuthConf{EncryptResponse: true})
+	req = httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(`{"name":"debug"}`))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	req.Header.Set(DebugHeader, "test")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	assert.Equal(t, "", w.Header().Get(service.EncryptHeader))
+	assert.Equal(t, map[string]interface{}{"name": "debug"}, decryptResponse(t, w, "").Data)
+
+	// ServiceContext.Error 按照 X-Language 翻译
+	req = httptest.NewRequest(http.MethodPost, "/auth", strings.NewReader(`{`))
+	req.Header.Set("Content-Type", gin.MIMEJSON)
+	req.Header.Set(DebugHeader, "test")
+	req.Header.Set(LanguageHeader, "zh")
+	req.Header.Set("Accept-Language", "en")
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	resp := decryptResponse(t, w, "")
+	assert.Equal(t, code_err.ParamsErr.Code, resp.Code)
+	assert.Equal(t, "参数错误", resp.Msg)
+}
@@ -0,0 +1,296 @@
+package interceptor
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/go-redis/redis/v8"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/db/eredis"
+	"github.com/weblazy/easy/ectx"
+	"github.com/weblazy/easy/elog"
+	"go.uber.org/zap"
+)
+
+const (
+	IdempotencyHeader         = "Idempotency-Key"
+	IdempotencyReplayedHeader = "Idempotent-Replayed" // 重放保存的响应时返回 true
+	DefaultIdempotencyPrefix  = "idempotency#"
+)
+
+// IdempotencyStore 保存幂等请求的处理状态和响应
+type IdempotencyStore interface {
+	// Lock 记录正在处理的请求, key 已经存在时返回 false
+	Lock(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error)
+	// Get 返回保存的内容, key 不存在时返回 nil
+	Get(ctx context.Context, key string) ([]byte, error)
+	// CompareAndSet 当前内容为 old 时替换为 value, 锁已经过期或被其他请求持有时返回 false
+	CompareAndSet(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error)
+	// CompareAndDelete 当前内容为 old 时删除
+	CompareAndDelete(ctx context.Context, key string, old []byte) (bool, error)
+}
+
+// IdempotencyConf 幂等配置
+type IdempotencyConf struct {
+	Store       IdempotencyStore
+	TTL         time.Duration // 响应保存的时间, 默认 24h
+	LockTimeout time.Duration // 请求处理的最长时间, 超时后锁自动释放, 默认 1min
+	Required    bool          // 没有 Idempotency-Key 时返回 code_err.ParamsErr, 默认不处理
+}
+
+// idempotencyRecord 请求处理中只有 Fingerprint 和 Owner, 处理完成后保存响应
+// Owner 为每个请求随机生成, 处理时间超过 LockTimeout 后不会覆盖或删除其他请求的锁
+type idempotencyRecord struct {
+	Fingerprint string      `json:"fingerprint"`
+	Owner       string      `json:"owner,omitempty"`
+	Done        bool        `json:"done"`
+	Status      int         `json:"status,omitempty"`
+	Header      http.Header `json:"header,omitempty"`
+	Body        []byte      `json:"body,omitempty"`
+}
+
+// Idempotency 使用 Idempotency-Key 保证非 GET 请求只处理一次, key 按照 X-Uid 隔离, 需要放在 Token 之后
+// 第一个请求处理期间相同 key 的请求返回 IdempotencyConflictErr, 处理完成后返回保存的响应
+// method, path 或 body 不同时返回 IdempotencyMismatchErr, 响应为 5xx 时不保存, 客户端可以重试
+// 存储异常时不做幂等处理
+func Idempotency(conf *IdempotencyConf) gin.HandlerFunc {
+	if conf.TTL <= 0 {
+		conf.TTL = 24 * time.Hour
+	}
+	if conf.LockTimeout <= 0 {
+		conf.LockTimeout = time.Minute
+	}
+	return func(c *gin.Context) {
+		switch c.Request.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			return
+		}
+		idempotencyKey := c.GetHeader(IdempotencyHeader)
+		if idempotencyKey == "" {
+			if conf.Required {
+				Error(c, code_err.ParamsErr, fmt.Errorf("%s required", IdempotencyHeader))
+			}
+			return
+		}
+		bodyBytes, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			Error(c, code_err.ParamsErr, fmt.Errorf("Invalid request body"))
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewBuffer(bodyBytes))
+
+		// 客户端断开后仍然需要保存响应
+		ctx := ectx.NewNoCancelContext(c.Request.Context())
+		key := c.GetHeader(UidHeader) + "#" + idempotencyKey
+		fingerprint := Sha256ToHex([]byte(c.Request.Method + " " + c.Request.URL.Path + "\n" + string(bodyBytes)))
+		lock, _ := json.Marshal(&idempotencyRecord{Fingerprint: fingerprint, Owner: idempotencyOwner()})
+		ok, err := conf.Store.Lock(ctx, key, lock, conf.LockTimeout)
+		if err != nil {
+			elog.ErrorCtx(ctx, "idempotency lock", elog.FieldError(err))
+			return
+		}
+		if !ok {
+			replayIdempotent(c, conf.Store, key, fingerprint)
+			return
+		}
+
+		defer func() {
+			if r := recover(); r != nil {
+				_, _ = conf.Store.CompareAndDelete(ctx, key, lock)
+				panic(r)
+			}
+		}()
+		blw := &BodyLogWriter{body: bytes.NewBufferString(""), ResponseWriter: c.Writer}
+		c.Writer = blw
+		c.Next()
+		c.Writer = blw.ResponseWriter
+
+		if c.Writer.Status() >= http.StatusInternalServerError {
+			if _, err := conf.Store.CompareAndDelete(ctx, key, lock); err != nil {
+				elog.ErrorCtx(ctx, "idempotency unlock", elog.FieldError(err))
+			}
+			return
+		}
+		record, _ := json.Marshal(&idempotencyRecord{
+			Fingerprint: fingerprint,
+			Done:        true,
+			Status:      c.Writer.Status(),
+			Header:      c.Writer.Header().Clone(),
+			Body:        blw.body.Bytes(),
+		})
+		ok, err = conf.Store.CompareAndSet(ctx, key, lock, record, conf.TTL)
+		if err != nil {
+			elog.ErrorCtx(ctx, "idempotency save", elog.FieldError(err))
+		} else if !ok {
+			elog.WarnCtx(ctx, "idempotency lock expired before response saved", zap.String("key", key), zap.Duration("lock_timeout", conf.LockTimeout))
+		}
+	}
+}
+
+func idempotencyOwner() string {
+	b := make([]byte, 16)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func replayIdempotent(c *gin.Context, store IdempotencyStore, key, fingerprint string) {
+	data, err := store.Get(c.Request.Context(), key)
+	if err != nil {
+		Error(c, code_err.SystemErr, err)
+		return
+	}
+	// 锁在两次调用之间过期, 让客户端重试
+	if data == nil {
+		Error(c, code_err.IdempotencyConflictErr, errors.New("idempotency lock expired"))
+		return
+	}
+	record := &idempotencyRecord{}
+	if err := json.Unmarshal(data, record); err != nil {
+		Error(c, code_err.SystemErr, err)
+		return
+	}
+	if record.Fingerprint != fingerprint {
+		Error(c, code_err.IdempotencyMismatchErr, fmt.Errorf("idempotency key %s reused", key))
+		return
+	}
+	if !record.Done {
+		Error(c, code_err.IdempotencyConflictErr, fmt.Errorf("idempotency key %s in progress", key))
+		return
+	}
+	header := c.Writer.Header()
+	for k, v := range record.Header {
+		header[k] = v
+	}
+	header.Set(IdempotencyReplayedHeader, "true")
+	c.Writer.WriteHeader(record.Status)
+	_, _ = c.Writer.Write(record.Body)
+	c.Abort()
+}
+
+// MemoryIdempotencyStore 内存存储, 适用于单实例部署和单测
+type MemoryIdempotencyStore struct {
+	mu      sync.Mutex
+	records map[string]memoryIdempotencyItem
+}
+
+type memoryIdempotencyItem struct {
+	value    []byte
+	expireAt time.Time
+}
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{records: make(map[string]memoryIdempotencyItem)}
+}
+
+func (s *MemoryIdempotencyStore) Lock(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := time.Now()
+	// 写入时清理已过期的记录
+	for k, item := range s.records {
+		if now.After(item.expireAt) {
+			delete(s.records, k)
+		}
+	}
+	if _, ok := s.records[key]; ok {
+		return false, nil
+	}
+	s.records[key] = memoryIdempotencyItem{value: value, expireAt: now.Add(expire)}
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	item, ok := s.records[key]
+	if !ok || time.Now().After(item.expireAt) {
+		return nil, nil
+	}
+	return item.value, nil
+}
+
+func (s *MemoryIdempotencyStore) CompareAndSet(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.match(key, old) {
+		return false, nil
+	}
+	s.records[key] = memoryIdempotencyItem{value: value, expireAt: time.Now().Add(expire)}
+	return true, nil
+}
+
+func (s *MemoryIdempotencyStore) CompareAndDelete(ctx context.Context, key string, old []byte) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.match(key, old) {
+		return false, nil
+	}
+	delete(s.records, key)
+	return true, nil
+}
+
+// match 未过期并且内容为 value, 需要持有锁
+func (s *MemoryIdempotencyStore) match(key string, value []byte) bool {
+	item, ok := s.records[key]
+	return ok && time.Now().Before(item.expireAt) && bytes.Equal(item.value, value)
+}
+
+var (
+	compareAndSetScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[2], "PX", ARGV[3])
+	return 1
+end
+return 0`)
+	compareAndDeleteScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0`)
+)
+
+// RedisIdempotencyStore 基于 redis 的存储, 适用于多实例部署
+type RedisIdempotencyStore struct {
+	client *eredis.RedisClient
+	prefix string
+}
+
+// NewRedisIdempotencyStore 创建 redis 存储, prefix 为空时使用 DefaultIdempotencyPrefix
+func NewRedisIdempotencyStore(client *eredis.RedisClient, prefix string) *RedisIdempotencyStore {
+	if prefix == "" {
+		prefix = DefaultIdempotencyPrefix
+	}
+	return &RedisIdempotencyStore{client: client, prefix: prefix}
+}
+
+func (s *RedisIdempotencyStore) Lock(ctx context.Context, key string, value []byte, expire time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+key, value, expire).Result()
+}
+
+func (s *RedisIdempotencyStore) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := s.client.Get(ctx, s.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, nil
+	}
+	return data, err
+}
+
+func (s *RedisIdempotencyStore) CompareAndSet(ctx context.Context, key string, old, value []byte, expire time.Duration) (bool, error) {
+	n, err := compareAndSetScript.Run(ctx, s.client, []string{s.prefix + key}, old, value, expire.Milliseconds()).Int()
+	return n == 1, err
+}
+
+func (s *RedisIdempotencyStore) CompareAndDelete(ctx context.Context, key string, old []byte) (bool, error) {
+	n, err := compareAndDeleteScript.Run(ctx, s.client, []string{s.prefix + key}, old).Int()
+	return n == 1, err
+}
@@ -0,0 +1,172 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/http/http_server/service"
+)
+
+func idempotentRequest(r *gin.Engine, uid, key, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodPost, "/pay", strings.NewReader(body))
+	req.Header.Set(UidHeader, uid)
+	if key != "" {
+		req.Header.Set(IdempotencyHeader, key)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func responseCode(t *testing.T, w *httptest.ResponseRecorder) int64 {
+	resp := &service.Response{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), resp))
+	return resp.Code
+}
+
+func TestIdempotency(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var count int64
+	block := make(chan struct{})
+	started := make(chan struct{})
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.POST("/pay", Idempotency(&IdempotencyConf{Store: NewMemoryIdempotencyStore()}), func(c *gin.Context) {
+		n := atomic.AddInt64(&count, 1)
+		switch c.GetHeader(IdempotencyHeader) {
+		case "slow":
+			close(started)
+			<-block
+		case "fail":
+			if n == 1 {
+				c.Status(http.StatusInternalServerError)
+				return
+			}
+		case "panic":
+			if n == 1 {
+				panic("pay")
+			}
+		}
+		c.Header("X-Order", "o1")
+		c.JSON(http.StatusCreated, &service.Response{Data: n})
+	})
+
+	w := idempotentRequest(r, "1", "k1", `{"amount":1}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	first := w.Body.String()
+
+	// 重放保存的响应
+	w = idempotentRequest(r, "1", "k1", `{"amount":1}`)
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, first, w.Body.String())
+	assert.Equal(t, "o1", w.Header().Get("X-Order"))
+	assert.Equal(t, "true", w.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, int64(1), atomic.LoadInt64(&count))
+
+	// body 不同
+	w = idempotentRequest(r, "1", "k1", `{"amount":2}`)
+	assert.Equal(t, code_err.IdempotencyMismatchErr.Code, responseCode(t, w))
+
+	// 不同用户的 key 互不影响
+	idempotentRequest(r, "2", "k1", `{"amount":1}`)
+	assert.Equal(t, int64(2), atomic.LoadInt64(&count))
+
+	// 没有 key 时不处理
+	idempotentRequest(r, "1", "", `{"amount":1}`)
+	idempotentRequest(r, "1", "", `{"amount":1}`)
+	assert.Equal(t, int64(4), atomic.LoadInt64(&count))
+
+	// 处理中的重复请求
+	done := make(chan struct{})
+	go func() {
+		idempotentRequest(r, "1", "slow", `{}`)
+		close(done)
+	}()
+	<-started
+	w = idempotentRequest(r, "1", "slow", `{}`)
+	assert.Equal(t, code_err.IdempotencyConflictErr.Code, responseCode(t, w))
+	close(block)
+	<-done
+	assert.Equal(t, int64(5), atomic.LoadInt64(&count))
+
+	// 5xx 和 panic 不保存, 可以重试
+	atomic.StoreInt64(&count, 0)
+	assert.Equal(t, http.StatusInternalServerError, idempotentRequest(r, "1", "fail", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, idempotentRequest(r, "1", "fail", `{}`).Code)
+	atomic.StoreInt64(&count, 0)
+	assert.Equal(t, http.StatusInternalServerError, idempotentRequest(r, "1", "panic", `{}`).Code)
+	assert.Equal(t, http.StatusCreated, idempotentRequest(r, "1", "panic", `{}`).Code)
+}
+
+func TestIdempotency_Required(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.POST("/pay", Idempotency(&IdempotencyConf{Store: NewMemoryIdempotencyStore(), Required: true}), func(c *gin.Context) {
+		c.JSON(http.StatusOK, &service.Response{})
+	})
+	assert.Equal(t, code_err.ParamsErr.Code, responseCode(t, idempotentRequest(r, "1", "", `{}`)))
+	assert.Equal(t, int64(0), responseCode(t, idempotentRequest(r, "1", "k1", `{}`)))
+}
+
+func TestIdempotency_LockTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var count int64
+	started := make(chan struct{})
+	block := make(chan struct{})
+	r := gin.New()
+	r.POST("/pay", Idempotency(&IdempotencyConf{Store: NewMemoryIdempotencyStore(), LockTimeout: 20 * time.Millisecond}), func(c *gin.Context) {
+		n := atomic.AddInt64(&count, 1)
+		if n == 1 {
+			close(started)
+			<-block
+		}
+		c.JSON(http.StatusOK, &service.Response{Data: n})
+	})
+
+	// 第一个请求处理时间超过 LockTimeout, 锁过期后第二个请求获得锁并保存响应
+	done := make(chan *httptest.ResponseRecorder)
+	go func() {
+		done <- idempotentRequest(r, "1", "k1", `{}`)
+	}()
+	<-started
+	time.Sleep(30 * time.Millisecond)
+	second := idempotentRequest(r, "1", "k1", `{}`)
+	assert.Equal(t, http.StatusOK, second.Code)
+	close(block)
+	<-done
+
+	// 第一个请求结束后不会覆盖第二个请求保存的响应
+	w := idempotentRequest(r, "1", "k1", `{}`)
+	assert.Equal(t, second.Body.String(), w.Body.String())
+	assert.Equal(t, "true", w.Header().Get(IdempotencyReplayedHeader))
+	assert.Equal(t, int64(2), atomic.LoadInt64(&count))
+}
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	ctx := context.Background()
+	store := NewMemoryIdempotencyStore()
+	ok, err := store.Lock(ctx, "k", []byte("a"), time.Minute)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = store.CompareAndSet(ctx, "k", []byte("b"), []byte("c"), time.Minute)
+	assert.False(t, ok)
+	ok, _ = store.CompareAndDelete(ctx, "k", []byte("b"))
+	assert.False(t, ok)
+	ok, _ = store.CompareAndSet(ctx, "k", []byte("a"), []byte("c"), time.Minute)
+	assert.True(t, ok)
+	data, _ := store.Get(ctx, "k")
+	assert.Equal(t, []byte("c"), data)
+	ok, _ = store.CompareAndDelete(ctx, "k", []byte("c"))
+	assert.True(t, ok)
+	data, _ = store.Get(ctx, "k")
+	assert.Nil(t, data)
+}
@@ -76,6 +76,7 @@ func LogJson(c *gin.Context, cfg *http_server_config.Config) {
 			zap.String("url", req.URL.String()),
 			zap.String("host", req.Host),
 			zap.String("path", req.URL.Path),
+			zap.String("route", RoutePath(c, cfg)),
 			elog.FieldMethod(req.Method),
 			zap.Any("req_header", req.Header),
 			zap.String("req_body", logData.RequestBody),
@@ -131,6 +132,7 @@ func LogFile(c *gin.Context, cfg *http_server_config.Config) {
 			zap.String("url", req.URL.String()),
 			zap.String("host", req.Host),
 			zap.String("path", req.URL.Path),
+			zap.String("route", RoutePath(c, cfg)),
 			elog.FieldMethod(req.Method),
 			zap.Any("req_header", req.Header),
 			zap.String("req_body", logData.RequestBody),
@@ -173,6 +175,7 @@ func Error(c *gin.Context, codeErr *code_err.CodeErr, err error) {
 	if ok {
 		elog.InfoCtx(c.Request.Context(), fmt.Sprintf("%s:%d %s", file, line, err.Error()))
 	}
+	codeErr = codeErr.Translate(service.Language(c))
 	resp := &service.Response{
 		Code: codeErr.Code,
 		Msg:  codeErr.Msg,
@@ -23,19 +23,52 @@ var (
 		Namespace: "",
 		Name:      "http_server_handle_seconds",
 	}, []string{"name", "method", "path", "host"})
+
+	// ServerTimeoutCounter Timeout 中间件返回超时的请求
+	ServerTimeoutCounter = prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Namespace: "",
+			Name:      "http_server_timeout_total",
+		}, []string{"name", "method", "path"})
 )
 
 func init() {
 	prometheus.MustRegister(ServerHandleCounter)
 	prometheus.MustRegister(ServerHandleHistogram)
+	prometheus.MustRegister(ServerTimeoutCounter)
 }
+
+// RoutePath 指标和日志使用的 path, MetricPathAllowlist 中的路径使用原始 path, 其他使用 gin 匹配的路由模板 (c.FullPath())
+// 没有匹配路由时统一为 http_server_config.UnmatchedPath, 最后经过 MetricPathRewriter 重写
+func RoutePath(c *gin.Context, cfg *http_server_config.Config) string {
+	path := c.Request.URL.Path
+	allowed := false
+	for _, p := range cfg.MetricPathAllowlist {
+		if p == path {
+			allowed = true
+			break
+		}
+	}
+	if !allowed {
+		path = c.FullPath()
+		if path == "" {
+			return http_server_config.UnmatchedPath
+		}
+	}
+	if cfg.MetricPathRewriter == nil {
+		return path
+	}
+	return cfg.MetricPathRewriter(path)
+}
+
 func MetricInterceptor(cfg *http_server_config.Config) gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
-		if cfg.MetricPathRewriter == nil {
-			cfg.MetricPathRewriter = http_server_config.DefaultMetricPathRewriter
+		path := RoutePath(c, cfg)
+		ServerHandleCounter.WithLabelValues(cfg.Name, c.Request.Method, path, c.Request.URL.Host, strconv.Itoa(c.Writer.Status())).Inc()
+		ServerHandleHistogram.WithLabelValues(cfg.Name, c.Request.Method, path, c.Request.URL.Host).Observe(time.Since(GetStartTime(c.Request.Context())).Seconds())
+		if c.GetBool(TimedOutKey) {
+			ServerTimeoutCounter.WithLabelValues(cfg.Name, c.Request.Method, path).Inc()
 		}
-		ServerHandleCounter.WithLabelValues(cfg.Name, c.Request.Method, cfg.MetricPathRewriter(c.Request.URL.Path), c.Request.URL.Host, strconv.Itoa(c.Writer.Status())).Inc()
-		ServerHandleHistogram.WithLabelValues(cfg.Name, c.Request.Method, cfg.MetricPathRewriter(c.Request.URL.Path), c.Request.URL.Host).Observe(time.Since(GetStartTime(c.Request.Context())).Seconds())
 	}
 }
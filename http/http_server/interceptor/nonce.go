@@ -0,0 +1,63 @@
+package interceptor
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/weblazy/easy/cache"
+	"github.com/weblazy/easy/db/eredis"
+)
+
+// DefaultNoncePrefix redis 中 nonce 的 key 前缀
+const DefaultNoncePrefix = "nonce#"
+
+// NonceStore 记录已使用的 nonce, 防止请求重放
+type NonceStore interface {
+	// Add 记录 nonce, expire 内已经存在时返回 false
+	Add(ctx context.Context, nonce string, expire time.Duration) (bool, error)
+}
+
+// MemoryNonceStore 基于 cache.Cache 的 nonce 存储, 适用于单实例部署
+type MemoryNonceStore struct {
+	mu    sync.Mutex
+	cache *cache.Cache
+}
+
+// NewMemoryNonceStore 创建内存 nonce 存储, expire 为 nonce 的保存时间, 需要不小于签名的时间窗口
+// 内存存储所有 nonce 使用相同的过期时间, Add 的 expire 参数会被忽略
+func NewMemoryNonceStore(expire time.Duration) (*MemoryNonceStore, error) {
+	c, err := cache.NewCache(expire, cache.WithName("nonce"))
+	if err != nil {
+		return nil, err
+	}
+	return &MemoryNonceStore{cache: c}, nil
+}
+
+func (s *MemoryNonceStore) Add(ctx context.Context, nonce string, expire time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.cache.Get(nonce); ok {
+		return false, nil
+	}
+	s.cache.Set(nonce, emptyData)
+	return true, nil
+}
+
+// RedisNonceStore 基于 redis SETNX 的 nonce 存储, 适用于多实例部署
+type RedisNonceStore struct {
+	client *eredis.RedisClient
+	prefix string
+}
+
+// NewRedisNonceStore 创建 redis nonce 存储, prefix 为空时使用 DefaultNoncePrefix
+func NewRedisNonceStore(client *eredis.RedisClient, prefix string) *RedisNonceStore {
+	if prefix == "" {
+		prefix = DefaultNoncePrefix
+	}
+	return &RedisNonceStore{client: client, prefix: prefix}
+}
+
+func (s *RedisNonceStore) Add(ctx context.Context, nonce string, expire time.Duration) (bool, error) {
+	return s.client.SetNX(ctx, s.prefix+nonce, 1, expire).Result()
+}
@@ -1,35 +1,224 @@
 package interceptor
 
 import (
+	"bufio"
+	"bytes"
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/elog"
+	"github.com/weblazy/easy/http/http_server/service"
 )
 
-// timeout middleware wraps the request context with a timeout
-func Timeout(timeout time.Duration) func(c *gin.Context) {
-	return func(c *gin.Context) {
+// TimedOutKey 请求超时时在 gin.Context 中设置为 true, 用于监控
+const TimedOutKey = "easy_timed_out"
+
+// TimeoutConf 超时配置
+type TimeoutConf struct {
+	Timeout time.Duration            // 默认超时时间, 0 为不限制
+	Routes  map[string]time.Duration // 路由模板的超时时间, 如 /v1/user/:id, 优先于 Timeout, 0 为不限制
+}
+
+func (conf *TimeoutConf) timeout(fullPath string) time.Duration {
+	if timeout, ok := conf.Routes[fullPath]; ok {
+		return timeout
+	}
+	return conf.Timeout
+}
+
+// Timeout 所有路由使用相同的超时时间
+func Timeout(timeout time.Duration) gin.HandlerFunc {
+	return TimeoutWithConf(&TimeoutConf{Timeout: timeout})
+}
 
-		// wrap the request context with a timeout
+// TimeoutWithConf 请求处理超时后立即返回 504 和 code_err.TimeoutErr
+// handler 在单独的 goroutine 中执行, 响应先写入缓冲区, 超时后的写入被丢弃
+// 超时后仍然等待 handler 结束再返回, 防止 gin.Context 被下一个请求复用, handler 需要检查 context 及时退出
+// 缓冲区不支持 Flush 和 Hijack, websocket 和 SSE 请求不做处理, 文件下载等路由需要设置超时时间为 0
+func TimeoutWithConf(conf *TimeoutConf) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		timeout := conf.timeout(c.FullPath())
+		if timeout <= 0 || isStreaming(c.Request) {
+			c.Next()
+			return
+		}
 		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		// handler 可能修改请求, 在启动 goroutine 之前读取
+		language := service.Language(c)
+		method := c.Request.Method
 
-		defer func() {
-			// check if context timeout was reached
-			if ctx.Err() == context.DeadlineExceeded {
+		origin := c.Writer
+		w := newTimeoutWriter(origin)
+		c.Writer = w
+		done := make(chan interface{}, 1)
+		go func() {
+			defer func() {
+				done <- recover()
+			}()
+			c.Next()
+		}()
 
-				// write response and abort the request
-				c.Writer.WriteHeader(http.StatusGatewayTimeout)
-				c.Abort()
+		var p interface{}
+		select {
+		case p = <-done:
+		case <-ctx.Done():
+			if !errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				// 客户端断开, 等待 handler 结束
+				p = <-done
+				break
 			}
+			w.timeout()
+			c.Set(TimedOutKey, true)
+			writeTimeout(origin, language)
+			elog.WarnCtx(ctx, "http request timeout", elog.FieldMethod(method), elog.FieldError(fmt.Errorf("timeout %s", timeout)))
+			if p = <-done; p != nil {
+				// 超时响应已经返回, 只记录日志
+				elog.ErrorCtx(ctx, "http handler panic after timeout", elog.FieldError(fmt.Errorf("%v", p)))
+			}
+			c.Writer = origin
+			return
+		}
+		c.Writer = origin
+		if p != nil {
+			panic(p)
+		}
+		w.flush()
+	}
+}
 
-			//cancel to clear resources after finished
-			cancel()
-		}()
+// isStreaming websocket 等协议升级请求需要 Hijack, SSE 请求需要 Flush
+func isStreaming(req *http.Request) bool {
+	for _, v := range req.Header.Values("Connection") {
+		for _, token := range strings.Split(v, ",") {
+			if strings.EqualFold(strings.TrimSpace(token), "upgrade") {
+				return true
+			}
+		}
+	}
+	return strings.Contains(req.Header.Get("Accept"), "text/event-stream")
+}
 
-		// replace request with context wrapped request
-		c.Request = c.Request.WithContext(ctx)
-		c.Next()
+func writeTimeout(w gin.ResponseWriter, language string) {
+	codeErr := code_err.TimeoutErr.Translate(language)
+	body, _ := json.Marshal(&service.Response{Code: codeErr.Code, Msg: codeErr.Msg})
+	header := w.Header()
+	header.Set("Content-Type", "application/json; charset=utf-8")
+	header.Set("Content-Length", strconv.Itoa(len(body)))
+	w.WriteHeader(http.StatusGatewayTimeout)
+	_, _ = w.Write(body)
+	// 立即发送给客户端, 不等待 handler 结束
+	w.Flush()
+}
+
+// timeoutWriter 缓存 handler 的响应, 超时后丢弃写入
+type timeoutWriter struct {
+	gin.ResponseWriter
+	mu          sync.Mutex
+	header      http.Header
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	timedOut    bool
+}
+
+func newTimeoutWriter(w gin.ResponseWriter) *timeoutWriter {
+	return &timeoutWriter{ResponseWriter: w, header: w.Header().Clone(), status: http.StatusOK}
+}
+
+func (w *timeoutWriter) Header() http.Header {
+	return w.header
+}
+
+func (w *timeoutWriter) WriteHeader(code int) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut || w.wroteHeader || code <= 0 {
+		return
+	}
+	w.status = code
+}
+
+func (w *timeoutWriter) WriteHeaderNow() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.wroteHeader = true
+}
+
+func (w *timeoutWriter) Write(b []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.timedOut {
+		return 0, http.ErrHandlerTimeout
+	}
+	w.wroteHeader = true
+	return w.body.Write(b)
+}
+
+func (w *timeoutWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *timeoutWriter) Status() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.status
+}
+
+func (w *timeoutWriter) Size() int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if !w.wroteHeader {
+		return -1
+	}
+	return w.body.Len()
+}
+
+func (w *timeoutWriter) Written() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.wroteHeader
+}
+
+// Flush 缓冲区在 handler 结束后才写入
+func (w *timeoutWriter) Flush() {}
+
+func (w *timeoutWriter) Hijack() (net.Conn, *bufio.ReadWriter, error) {
+	return nil, nil, errors.New("timeout writer does not support hijack")
+}
+
+func (w *timeoutWriter) Pusher() http.Pusher {
+	return nil
+}
+
+func (w *timeoutWriter) timeout() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.timedOut = true
+}
+
+// flush handler 结束后写入原始的 ResponseWriter, 没有写入时由 gin 写入默认的状态码
+func (w *timeoutWriter) flush() {
+	dst := w.ResponseWriter.Header()
+	for k, v := range w.header {
+		dst[k] = v
+	}
+	if !w.wroteHeader && w.status == http.StatusOK {
+		return
+	}
+	w.ResponseWriter.WriteHeader(w.status)
+	if w.body.Len() > 0 {
+		_, _ = w.ResponseWriter.Write(w.body.Bytes())
 	}
 }
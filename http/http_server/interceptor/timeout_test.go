@@ -0,0 +1,85 @@
+package interceptor
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/code_err"
+)
+
+func TestTimeout(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	late := make(chan error, 1)
+	timedOut := make(chan bool, 1)
+	r := gin.New()
+	r.Use(gin.Recovery())
+	r.Use(func(c *gin.Context) {
+		c.Next()
+		timedOut <- c.GetBool(TimedOutKey)
+	})
+	r.Use(TimeoutWithConf(&TimeoutConf{
+		Timeout: 50 * time.Millisecond,
+		Routes:  map[string]time.Duration{"/download/:name": 0, "/slow/:id": 10 * time.Millisecond},
+	}))
+	r.GET("/fast", func(c *gin.Context) {
+		c.Header("X-Fast", "1")
+		c.String(http.StatusCreated, "fast")
+	})
+	r.GET("/slow/:id", func(c *gin.Context) {
+		<-c.Request.Context().Done()
+		time.Sleep(20 * time.Millisecond)
+		_, err := c.Writer.WriteString("late")
+		late <- err
+	})
+	r.GET("/download/:name", func(c *gin.Context) {
+		time.Sleep(80 * time.Millisecond)
+		c.String(http.StatusOK, "file")
+	})
+	r.GET("/events", func(c *gin.Context) {
+		c.String(http.StatusOK, "data: 1\n\n")
+		c.Writer.Flush()
+	})
+	r.GET("/panic", func(c *gin.Context) {
+		panic("timeout")
+	})
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/fast", nil))
+	assert.Equal(t, http.StatusCreated, w.Code)
+	assert.Equal(t, "fast", w.Body.String())
+	assert.Equal(t, "1", w.Header().Get("X-Fast"))
+	assert.False(t, <-timedOut)
+
+	// 超时返回 504, 超时后的写入被丢弃
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/slow/1", nil))
+	assert.Equal(t, http.StatusGatewayTimeout, w.Code)
+	assert.Equal(t, code_err.TimeoutErr.Code, responseCode(t, w))
+	assert.Equal(t, http.ErrHandlerTimeout, <-late)
+	assert.True(t, <-timedOut)
+
+	// 路由超时时间为 0 时不限制
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/download/a", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "file", w.Body.String())
+	<-timedOut
+
+	// SSE 请求不使用缓冲区, 可以 Flush
+	w = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/events", nil)
+	req.Header.Set("Accept", "text/event-stream")
+	r.ServeHTTP(w, req)
+	assert.True(t, w.Flushed)
+	assert.Equal(t, "data: 1\n\n", w.Body.String())
+	<-timedOut
+
+	// 没有超时的 panic 交给 Recovery 处理
+	w = httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/panic", nil))
+	assert.Equal(t, http.StatusInternalServerError, w.Code)
+}
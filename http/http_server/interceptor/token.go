@@ -2,12 +2,18 @@ package interceptor
 
 import (
 	"bytes"
+	"context"
+	"errors"
 	"fmt"
 	"io"
+	"strconv"
+	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/weblazy/easy/code_err"
 	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/elog"
 )
 
 // Token
@@ -33,8 +39,42 @@ func Token(userIdHeader string, validateToken func(token string) (uid string, er
 	}
 }
 
-// Sign
+// SignConf 验签防重放配置
+type SignConf struct {
+	Window     time.Duration // X-Timestamp 与服务器时间允许的最大偏差, 超出返回 code_err.ExpiredErr, 0 为不校验
+	NonceStore NonceStore    // 已使用的 nonce, 时间窗口内重复的 nonce 返回 code_err.ReplayErr, 为空时不校验
+}
+
+var (
+	defaultNonceStore     NonceStore
+	defaultNonceStoreOnce sync.Once
+)
+
+// DefaultSignConf 默认 5 分钟时间窗口, 所有路由共用一个内存 nonce 存储, 多实例部署时需要使用 NewRedisNonceStore
+func DefaultSignConf() *SignConf {
+	conf := &SignConf{Window: 5 * time.Minute}
+	defaultNonceStoreOnce.Do(func() {
+		store, err := NewMemoryNonceStore(2 * conf.Window)
+		if err != nil {
+			elog.ErrorCtx(context.Background(), "new memory nonce store", elog.FieldError(err))
+			return
+		}
+		defaultNonceStore = store
+	})
+	conf.NonceStore = defaultNonceStore
+	return conf
+}
+
+// Sign 只验签, 不校验时间窗口和 nonce, 防重放使用 SignWithConf(DefaultSignConf())
 func Sign() gin.HandlerFunc {
+	return SignWithConf(nil)
+}
+
+// SignWithConf 验证 body+timestamp+nonce 的 HMAC 签名, 验签通过后校验时间窗口和 nonce 防止请求重放
+func SignWithConf(conf *SignConf) gin.HandlerFunc {
+	if conf == nil {
+		conf = &SignConf{}
+	}
 	return func(c *gin.Context) {
 		req := c.Request
 		header := req.Header
@@ -60,6 +100,48 @@ func Sign() gin.HandlerFunc {
 				Error(c, code_err.SignErr, err)
 				return
 			}
+			if err := checkReplay(c.Request.Context(), conf, timestamp, nonce); err != nil {
+				Error(c, err, errors.New(err.DebugMsg))
+				return
+			}
 		}
 	}
 }
+
+// checkReplay 时间戳支持秒和毫秒
+func checkReplay(ctx context.Context, conf *SignConf, timestamp, nonce string) *code_err.CodeErr {
+	if conf.Window <= 0 {
+		return nil
+	}
+	ts, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return code_err.ExpiredErr.WithDebugMsg("invalid timestamp " + timestamp)
+	}
+	var t time.Time
+	if ts > 1e12 {
+		t = time.UnixMilli(ts)
+	} else {
+		t = time.Unix(ts, 0)
+	}
+	if d := time.Since(t); d > conf.Window || d < -conf.Window {
+		return code_err.ExpiredErr.WithDebugMsg("timestamp " + timestamp + " out of window")
+	}
+
+	if conf.NonceStore == nil {
+		return nil
+	}
+	if nonce == "" {
+		return code_err.ReplayErr.WithDebugMsg("nonce required")
+	}
+	// 时间戳可以早于或晚于服务器时间一个窗口, nonce 需要保存两个窗口
+	ok, err := conf.NonceStore.Add(ctx, nonce, 2*conf.Window)
+	if err != nil {
+		// nonce 存储异常时放行, 签名和时间窗口已经校验通过
+		elog.ErrorCtx(ctx, "add nonce", elog.FieldError(err))
+		return nil
+	}
+	if !ok {
+		return code_err.ReplayErr.WithDebugMsg("nonce " + nonce + " already used")
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package interceptor
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/econfig"
+	"github.com/weblazy/easy/econfig/eviper"
+	"github.com/weblazy/easy/http/http_server/service"
+)
+
+func signRequest(t *testing.T, r *gin.Engine, body, timestamp, nonce string) int64 {
+	return signRequestPath(t, r, "/sign", body, timestamp, nonce)
+}
+
+func signRequestPath(t *testing.T, r *gin.Engine, path, body, timestamp, nonce string) int64 {
+	sign, err := HmacSHA256Sign([]byte(nonce+timestamp), []byte(body+timestamp+nonce))
+	assert.Nil(t, err)
+	req := httptest.NewRequest(http.MethodPost, path, strings.NewReader(body))
+	req.Header.Set(SignHeader, sign)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(NonceHeader, nonce)
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	resp := &service.Response{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), resp))
+	return resp.Code
+}
+
+func TestSignWithConf(t *testing.T) {
+	econfig.GlobalViper = eviper.NewViperFromString("")
+	store, err := NewMemoryNonceStore(time.Minute)
+	assert.Nil(t, err)
+	r := gin.New()
+	r.POST("/sign", SignWithConf(&SignConf{Window: 30 * time.Second, NonceStore: store}), func(c *gin.Context) {
+		c.JSON(http.StatusOK, &service.Response{})
+	})
+
+	now := time.Now()
+	ts := strconv.FormatInt(now.Unix(), 10)
+	assert.Equal(t, int64(0), signRequest(t, r, `{"a":1}`, ts, "n1"))
+	// 重放
+	assert.Equal(t, code_err.ReplayErr.Code, signRequest(t, r, `{"a":1}`, ts, "n1"))
+	// 毫秒时间戳
+	assert.Equal(t, int64(0), signRequest(t, r, `{"a":1}`, strconv.FormatInt(now.UnixMilli(), 10), "n2"))
+	// 超出时间窗口
+	assert.Equal(t, code_err.ExpiredErr.Code, signRequest(t, r, `{"a":1}`, strconv.FormatInt(now.Add(-time.Minute).Unix(), 10), "n3"))
+	assert.Equal(t, code_err.ExpiredErr.Code, signRequest(t, r, `{"a":1}`, strconv.FormatInt(now.Add(time.Minute).Unix(), 10), "n4"))
+	assert.Equal(t, code_err.ExpiredErr.Code, signRequest(t, r, `{"a":1}`, "abc", "n5"))
+	// 缺少 nonce
+	assert.Equal(t, code_err.ReplayErr.Code, signRequest(t, r, `{"a":1}`, ts, ""))
+	// 签名错误时不记录 nonce
+	req := httptest.NewRequest(http.MethodPost, "/sign", strings.NewReader(`{}`))
+	req.Header.Set(TimestampHeader, ts)
+	req.Header.Set(NonceHeader, "n6")
+	r.ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, int64(0), signRequest(t, r, `{}`, ts, "n6"))
+}
+
+func TestSign(t *testing.T) {
+	econfig.GlobalViper = eviper.NewViperFromString("")
+	r := gin.New()
+	r.POST("/sign", Sign(), func(c *gin.Context) {
+		c.JSON(http.StatusOK, &service.Response{})
+	})
+	// 默认不校验时间窗口和 nonce
+	assert.Equal(t, int64(0), signRequest(t, r, `{"a":1}`, "abc", ""))
+	assert.Equal(t, int64(0), signRequest(t, r, `{"a":1}`, "abc", ""))
+
+	// 所有路由共用默认的 nonce 存储
+	r = gin.New()
+	handler := func(c *gin.Context) {
+		c.JSON(http.StatusOK, &service.Response{})
+	}
+	r.POST("/sign", SignWithConf(DefaultSignConf()), handler)
+	r.POST("/other", SignWithConf(DefaultSignConf()), handler)
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	assert.Equal(t, int64(0), signRequest(t, r, `{}`, ts, "shared"))
+	assert.Equal(t, code_err.ReplayErr.Code, signRequestPath(t, r, "/other", `{}`, ts, "shared"))
+}
+
+func TestMemoryNonceStore(t *testing.T) {
+	store, err := NewMemoryNonceStore(100 * time.Millisecond)
+	assert.Nil(t, err)
+	ok, err := store.Add(context.Background(), "n1", 0)
+	assert.Nil(t, err)
+	assert.True(t, ok)
+	ok, _ = store.Add(context.Background(), "n1", 0)
+	assert.False(t, ok)
+	assert.Eventually(t, func() bool {
+		ok, _ := store.Add(context.Background(), "n1", 0)
+		return ok
+	}, 2*time.Second, 50*time.Millisecond)
+}
@@ -0,0 +1,200 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"strings"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+	"github.com/weblazy/easy/code_err"
+	"github.com/weblazy/easy/http/http_server/service"
+)
+
+// Version 生成的 OpenAPI 版本
+const Version = "3.0.3"
+
+// Route 路由的接口描述
+type Route struct {
+	Summary     string
+	Description string
+	Tags        []string
+	Request     interface{}         // ServiceContext.BindValidator 使用的结构体, GET/DELETE 生成 query 参数, 其他方法生成 json body, uri tag 生成 path 参数
+	Response    interface{}         // ServiceContext.Success 返回的 data
+	Errors      []*code_err.CodeErr // 接口可能返回的业务错误
+	Deprecated  bool
+}
+
+// Spec 收集路由的接口描述, 根据 gin 的路由生成 OpenAPI 文档
+type Spec struct {
+	Info Info
+
+	mu       sync.RWMutex
+	routes   map[string]*Route // method + " " + 路由模板
+	ignores  map[string]struct{}
+	prefixes []string
+	codes    []*code_err.CodeErr
+}
+
+// New 创建 Spec, 默认包含 code_err 内置的错误码
+func New(title, version string) *Spec {
+	return &Spec{
+		Info:    Info{Title: title, Version: version},
+		routes:  make(map[string]*Route),
+		ignores: make(map[string]struct{}),
+		codes: []*code_err.CodeErr{
+			code_err.SystemErr,
+			code_err.ParamsErr,
+			code_err.TokenErr,
+			code_err.EncryptErr,
+			code_err.DecryptErr,
+			code_err.SignErr,
+			code_err.ExpiredErr,
+			code_err.ReplayErr,
+			code_err.IdempotencyConflictErr,
+			code_err.IdempotencyMismatchErr,
+			code_err.TimeoutErr,
+		},
+	}
+}
+
+func routeKey(method, fullPath string) string {
+	return method + " " + fullPath
+}
+
+// Annotate 描述已注册的路由, fullPath 为完整的路由模板, 如 /v1/user/:id
+func (s *Spec) Annotate(method, fullPath string, route Route) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.routes[routeKey(method, fullPath)] = &route
+}
+
+// Ignore 文档中不包含的路由, 如就绪检查
+func (s *Spec) Ignore(fullPaths ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, p := range fullPaths {
+		s.ignores[p] = struct{}{}
+	}
+}
+
+// IgnorePrefix 文档中不包含前缀下的路由, 如管理接口
+func (s *Spec) IgnorePrefix(prefixes ...string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.prefixes = append(s.prefixes, prefixes...)
+}
+
+func (s *Spec) ignored(fullPath string) bool {
+	if _, ok := s.ignores[fullPath]; ok {
+		return true
+	}
+	for _, prefix := range s.prefixes {
+		if fullPath == prefix || strings.HasPrefix(fullPath, strings.TrimSuffix(prefix, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// AddCodes 添加文档中列出的业务错误码
+func (s *Spec) AddCodes(errs ...*code_err.CodeErr) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.codes = append(s.codes, errs...)
+}
+
+// Handle 注册路由并添加描述
+func (s *Spec) Handle(group *gin.RouterGroup, method, relativePath string, route Route, handlers ...gin.HandlerFunc) gin.IRoutes {
+	s.Annotate(method, joinPaths(group.BasePath(), relativePath), route)
+	return group.Handle(method, relativePath, handlers...)
+}
+
+func (s *Spec) GET(group *gin.RouterGroup, relativePath string, route Route, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return s.Handle(group, http.MethodGet, relativePath, route, handlers...)
+}
+
+func (s *Spec) POST(group *gin.RouterGroup, relativePath string, route Route, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return s.Handle(group, http.MethodPost, relativePath, route, handlers...)
+}
+
+func (s *Spec) PUT(group *gin.RouterGroup, relativePath string, route Route, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return s.Handle(group, http.MethodPut, relativePath, route, handlers...)
+}
+
+func (s *Spec) DELETE(group *gin.RouterGroup, relativePath string, route Route, handlers ...gin.HandlerFunc) gin.IRoutes {
+	return s.Handle(group, http.MethodDelete, relativePath, route, handlers...)
+}
+
+// joinPaths 与 gin 拼接 group 路径的方式一致
+func joinPaths(absolutePath, relativePath string) string {
+	if relativePath == "" {
+		return absolutePath
+	}
+	finalPath := path.Join(absolutePath, relativePath)
+	if strings.HasSuffix(relativePath, "/") && !strings.HasSuffix(finalPath, "/") {
+		return finalPath + "/"
+	}
+	return finalPath
+}
+
+// Handler 返回 OpenAPI 文档, 每次请求根据当前的路由生成
+func (s *Spec) Handler(engine *gin.Engine) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.JSON(http.StatusOK, s.Build(engine.Routes()))
+	}
+}
+
+// Build 生成文档, 没有描述的路由只包含 service.Response 响应
+func (s *Spec) Build(routes gin.RoutesInfo) *Document {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	g := newGenerator()
+	doc := &Document{
+		OpenAPI: Version,
+		Info:    s.Info,
+		Paths:   make(map[string]PathItem),
+	}
+	envelope := g.schema(responseType)
+	g.schemas[g.names[responseType]].Properties["code"].Description = s.codeDescription()
+
+	for _, ri := range routes {
+		if s.ignored(ri.Path) {
+			continue
+		}
+		route := s.routes[routeKey(ri.Method, ri.Path)]
+		if route == nil {
+			route = &Route{}
+		}
+		p, pathParams := convertPath(ri.Path)
+		if doc.Paths[p] == nil {
+			doc.Paths[p] = make(PathItem)
+		}
+		doc.Paths[p][strings.ToLower(ri.Method)] = g.operation(ri.Method, pathParams, route, envelope)
+	}
+	doc.Components.Schemas = g.schemas
+	return doc
+}
+
+func (s *Spec) codeDescription() string {
+	lines := []string{fmt.Sprintf("业务状态码, %d 为成功", service.NewResponse().Code)}
+	for _, e := range s.codes {
+		lines = append(lines, fmt.Sprintf("%d: %s", e.Code, e.Msg))
+	}
+	return strings.Join(lines, "\n")
+}
+
+// convertPath gin 路由模板转换为 OpenAPI 路径, :id 和 *path 转换为 {id} 和 {path}
+func convertPath(fullPath string) (string, []string) {
+	var params []string
+	segments := strings.Split(fullPath, "/")
+	for i, seg := range segments {
+		if strings.HasPrefix(seg, ":") || strings.HasPrefix(seg, "*") {
+			params = append(params, seg[1:])
+			segments[i] = "{" + seg[1:] + "}"
+		}
+	}
+	return strings.Join(segments, "/"), params
+}
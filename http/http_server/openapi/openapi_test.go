@@ -0,0 +1,97 @@
+package openapi
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	"github.com/weblazy/easy/code_err"
+)
+
+type Page struct {
+	Page int `form:"page" json:"page"`
+	Size int `form:"size" json:"size"`
+}
+
+type ListUserReq struct {
+	Page
+	Name string `form:"name" binding:"required" description:"用户名"`
+}
+
+type User struct {
+	Id        int64     `json:"id" uri:"id" binding:"required"`
+	Name      string    `json:"name" binding:"required"`
+	Tags      []string  `json:"tags,omitempty"`
+	Parent    *User     `json:"parent" description:"上级"`
+	CreatedAt time.Time `json:"created_at"`
+	password  string
+	Ignored   string `json:"-"`
+}
+
+type ListUserResp struct {
+	List  []*User `json:"list"`
+	Total int64   `json:"total"`
+}
+
+var CustomErr = code_err.NewCodeErr(200001, "UserNotFound")
+
+func TestSpec_Build(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	spec := New("user", "1.0.0")
+	spec.AddCodes(CustomErr)
+	v1 := r.Group("/v1")
+	spec.GET(v1, "/users", Route{Summary: "用户列表", Tags: []string{"user"}, Request: ListUserReq{}, Response: ListUserResp{}}, func(c *gin.Context) {})
+	spec.PUT(v1, "/user/:id", Route{Request: &User{}, Response: &User{}, Errors: []*code_err.CodeErr{CustomErr}}, func(c *gin.Context) {})
+	r.GET("/files/*path", func(c *gin.Context) {})
+	r.GET("/readyz", func(c *gin.Context) {})
+	spec.Ignore("/readyz", "/openapi.json")
+	r.GET("/openapi.json", spec.Handler(r))
+
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/openapi.json", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	doc := &Document{}
+	assert.Nil(t, json.Unmarshal(w.Body.Bytes(), doc))
+
+	assert.Equal(t, Version, doc.OpenAPI)
+	assert.Len(t, doc.Paths, 3)
+
+	// query 参数, 匿名嵌入的结构体展开
+	list := doc.Paths["/v1/users"]["get"]
+	assert.Equal(t, "用户列表", list.Summary)
+	assert.Len(t, list.Parameters, 3)
+	assert.Equal(t, &Parameter{Name: "name", In: "query", Required: true, Description: "用户名", Schema: &Schema{Type: "string"}}, list.Parameters[2])
+	data := list.Responses["200"].Content["application/json"].Schema.AllOf
+	assert.Equal(t, "#/components/schemas/Response", data[0].Ref)
+	assert.Equal(t, "#/components/schemas/ListUserResp", data[1].Properties["data"].Ref)
+
+	// path 参数和 json body
+	update := doc.Paths["/v1/user/{id}"]["put"]
+	assert.Equal(t, []*Parameter{{Name: "id", In: "path", Required: true, Schema: &Schema{Type: "integer", Format: "int64"}}}, update.Parameters)
+	assert.Equal(t, "#/components/schemas/User", update.RequestBody.Content["application/json"].Schema.Ref)
+	assert.Contains(t, update.Responses["200"].Description, "200001: UserNotFound")
+
+	// 没有描述的路由
+	files := doc.Paths["/files/{path}"]["get"]
+	assert.Equal(t, "path", files.Parameters[0].Name)
+	assert.Equal(t, "#/components/schemas/Response", files.Responses["200"].Content["application/json"].Schema.Ref)
+
+	// components
+	user := doc.Components.Schemas["User"]
+	assert.Equal(t, []string{"id", "name"}, user.Required)
+	assert.Len(t, user.Properties, 5)
+	assert.Equal(t, &Schema{Type: "array", Items: &Schema{Type: "string"}}, user.Properties["tags"])
+	assert.Equal(t, &Schema{Type: "string", Format: "date-time"}, user.Properties["created_at"])
+	assert.Equal(t, "上级", user.Properties["parent"].Description)
+	assert.Equal(t, "#/components/schemas/User", user.Properties["parent"].AllOf[0].Ref)
+
+	envelope := doc.Components.Schemas["Response"]
+	assert.Len(t, envelope.Properties, 4)
+	assert.Contains(t, envelope.Properties["code"].Description, "100001: ParamsError")
+	assert.Contains(t, envelope.Properties["code"].Description, "200001: UserNotFound")
+}
@@ -0,0 +1,217 @@
+package openapi
+
+import (
+	"fmt"
+	"net/http"
+	"path"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/weblazy/easy/http/http_server/service"
+)
+
+var (
+	responseType = reflect.TypeOf(service.Response{})
+	timeType     = reflect.TypeOf(time.Time{})
+	invalidName  = regexp.MustCompile(`[^a-zA-Z0-9._-]`)
+)
+
+// generator 生成 schema, 结构体生成到 components 中, 使用 $ref 引用
+type generator struct {
+	schemas map[string]*Schema
+	names   map[reflect.Type]string
+}
+
+func newGenerator() *generator {
+	return &generator{
+		schemas: make(map[string]*Schema),
+		names:   make(map[reflect.Type]string),
+	}
+}
+
+func (g *generator) operation(method string, pathParams []string, route *Route, envelope *Schema) *Operation {
+	op := &Operation{
+		Summary:     route.Summary,
+		Description: route.Description,
+		Tags:        route.Tags,
+		Deprecated:  route.Deprecated,
+	}
+
+	// 响应为 service.Response, data 为 Route.Response
+	schema := envelope
+	if route.Response != nil {
+		schema = &Schema{AllOf: []*Schema{envelope, {
+			Type:       "object",
+			Properties: map[string]*Schema{"data": g.schema(reflect.TypeOf(route.Response))},
+		}}}
+	}
+	description := "成功"
+	if len(route.Errors) > 0 {
+		codes := make([]string, len(route.Errors))
+		for i, e := range route.Errors {
+			codes[i] = fmt.Sprintf("%d: %s", e.Code, e.Msg)
+		}
+		description += ", 业务错误码: " + strings.Join(codes, ", ")
+	}
+	op.Responses = map[string]Response{
+		"200": {Description: description, Content: map[string]MediaType{"application/json": {Schema: schema}}},
+	}
+
+	declared := make(map[string]bool)
+	if route.Request != nil {
+		t := indirect(reflect.TypeOf(route.Request))
+		if t.Kind() == reflect.Struct {
+			for _, p := range g.parameters(t, "uri", "path") {
+				declared[p.Name] = true
+				op.Parameters = append(op.Parameters, p)
+			}
+		}
+		if method == http.MethodGet || method == http.MethodDelete {
+			if t.Kind() == reflect.Struct {
+				op.Parameters = append(op.Parameters, g.parameters(t, "form", "query")...)
+			}
+		} else {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  map[string]MediaType{"application/json": {Schema: g.schema(t)}},
+			}
+		}
+	}
+	for _, name := range pathParams {
+		if !declared[name] {
+			op.Parameters = append(op.Parameters, &Parameter{Name: name, In: "path", Required: true, Schema: &Schema{Type: "string"}})
+		}
+	}
+	return op
+}
+
+// parameters 结构体中带有 tag 的字段生成参数, 与 gin 绑定 query 和 uri 使用的 tag 一致
+func (g *generator) parameters(t reflect.Type, tag, in string) []*Parameter {
+	var params []*Parameter
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.Anonymous && indirect(f.Type).Kind() == reflect.Struct && f.Tag.Get(tag) == "" {
+			params = append(params, g.parameters(indirect(f.Type), tag, in)...)
+			continue
+		}
+		name := tagName(f.Tag.Get(tag))
+		if !f.IsExported() || name == "-" || (name == "" && in == "path") {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		params = append(params, &Parameter{
+			Name:        name,
+			In:          in,
+			Description: f.Tag.Get("description"),
+			Required:    in == "path" || required(f),
+			Schema:      g.schema(f.Type),
+		})
+	}
+	return params
+}
+
+func (g *generator) schema(t reflect.Type) *Schema {
+	t = indirect(t)
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+	case reflect.String:
+		return &Schema{Type: "string"}
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+	case reflect.Struct:
+		return &Schema{Ref: "#/components/schemas/" + g.component(t)}
+	}
+	// interface{} 等任意类型
+	return &Schema{}
+}
+
+// component 结构体生成到 components 中, 返回名称, 不同包的同名结构体使用包名区分
+func (g *generator) component(t reflect.Type) string {
+	if name, ok := g.names[t]; ok {
+		return name
+	}
+	name := invalidName.ReplaceAllString(t.Name(), "_")
+	if name == "" {
+		name = fmt.Sprintf("Anonymous%d", len(g.names))
+	}
+	if _, ok := g.schemas[name]; ok {
+		name = invalidName.ReplaceAllString(path.Base(t.PkgPath()), "_") + "." + name
+	}
+	schema := &Schema{Type: "object", Properties: make(map[string]*Schema)}
+	// 先记录名称, 支持递归的结构体
+	g.names[t] = name
+	g.schemas[name] = schema
+	g.fields(t, schema)
+	return name
+}
+
+// fields 使用 json tag 作为字段名, 匿名嵌入的结构体展开, binding:"required" 为必填
+func (g *generator) fields(t reflect.Type, schema *Schema) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		name := tagName(f.Tag.Get("json"))
+		if f.Anonymous && indirect(f.Type).Kind() == reflect.Struct && name == "" {
+			g.fields(indirect(f.Type), schema)
+			continue
+		}
+		if !f.IsExported() || name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		s := g.schema(f.Type)
+		if description := f.Tag.Get("description"); description != "" {
+			if s.Ref != "" {
+				// $ref 的同级字段会被忽略
+				s = &Schema{AllOf: []*Schema{s}}
+			}
+			s.Description = description
+		}
+		schema.Properties[name] = s
+		if required(f) {
+			schema.Required = append(schema.Required, name)
+		}
+	}
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func tagName(tag string) string {
+	return strings.Split(tag, ",")[0]
+}
+
+func required(f reflect.StructField) bool {
+	for _, rule := range strings.Split(f.Tag.Get("binding"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
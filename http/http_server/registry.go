@@ -0,0 +1,73 @@
+package http_server
+
+import (
+	"context"
+	"time"
+
+	"github.com/weblazy/easy/closes"
+	"github.com/weblazy/easy/econfig/nacos"
+	"github.com/weblazy/easy/elog"
+	"go.uber.org/zap"
+)
+
+// initRegistrar 根据配置创建服务注册
+func (s *HttpServer) initRegistrar() {
+	if !s.Config.EnableRegistry {
+		return
+	}
+	config := s.Config.Registry
+	if config.ServiceName == "" {
+		config.ServiceName = s.Config.Name
+	}
+	if config.Port == 0 {
+		config.Port = uint64(s.Config.Port)
+	}
+	if config.Ip == "" && s.Config.Host != "0.0.0.0" {
+		config.Ip = s.Config.Host
+	}
+	s.registrar = nacos.NewRegistrar(config, nil)
+}
+
+// register 注册到 nacos, 程序退出时按 closes.RegistryPriority 最先注销
+func (s *HttpServer) register() error {
+	if s.registrar == nil {
+		return nil
+	}
+	if err := s.registrar.Register(); err != nil {
+		return err
+	}
+	closes.AddShutdown(closes.ModuleClose{
+		Name:     "http_server deregister",
+		Priority: closes.RegistryPriority,
+		Func: func() {
+			_ = s.drain(emptyCtx)
+		},
+	})
+	return nil
+}
+
+// deregister 从 nacos 注销, 可以重复调用
+func (s *HttpServer) deregister() {
+	if s.registrar != nil {
+		_ = s.registrar.Deregister()
+	}
+}
+
+// drain 从 nacos 注销并且就绪检查返回 503 后等待 DrainDelay, 让客户端摘除流量
+// 信号钩子, closes 和 GracefulStop 都会调用, 只执行一次
+func (s *HttpServer) drain(ctx context.Context) error {
+	s.drainOnce.Do(func() {
+		s.ready.Store(false)
+		s.deregister()
+		if s.Config.DrainDelay <= 0 || (s.registrar == nil && s.Config.ReadinessPath == "" && !s.Config.Admin.Enable) {
+			return
+		}
+		elog.InfoCtx(ctx, "http server not ready, waiting for drain", zap.Duration("drain_delay", s.Config.DrainDelay))
+		select {
+		case <-ctx.Done():
+			s.drainErr = ctx.Err()
+		case <-time.After(s.Config.DrainDelay):
+		}
+	})
+	return s.drainErr
+}
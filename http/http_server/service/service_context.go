@@ -2,14 +2,31 @@ package service
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/weblazy/crypto/aes"
 	"github.com/weblazy/easy/code_err"
 	"github.com/weblazy/easy/ectx"
+	"github.com/weblazy/easy/elog"
 )
 
+const (
+	// EncryptKey gin.Context 中响应加密密钥的 key, 由 interceptor.AuthWithConf 设置
+	EncryptKey = "easy_encrypt_key"
+	// EncryptHeader 响应已加密时返回 X-Encrypt: 1, body 为加密后的 json
+	EncryptHeader = "X-Encrypt"
+	// LanguageHeader 错误信息使用的语言, 优先于 Accept-Language
+	LanguageHeader = "X-Language"
+)
+
+// Language 根据 X-Language 和 Accept-Language 选择错误信息的语言
+func Language(c *gin.Context) string {
+	return code_err.NegotiateLanguage(c.GetHeader(LanguageHeader), c.GetHeader("Accept-Language"))
+}
+
 type ServiceContext struct {
 	*gin.Context
 	*code_err.Log
@@ -38,25 +55,27 @@ func NewServiceContext(g *gin.Context) *ServiceContext {
 // Success 返回正常数据
 func (c *ServiceContext) Success(data interface{}) {
 	c.R.Data = data
-	c.JSON(http.StatusOK, c.R)
+	c.render()
 }
 
-// Error 返回异常信息，自动识别Code码
+// Error 返回异常信息，自动识别Code码, *code_err.CodeErr 按请求的语言翻译
 func (c *ServiceContext) Error(err error) {
 	c.R.Code = defaultErrCode
+	c.R.Msg = err.Error()
 	if e, ok := err.(*code_err.CodeErr); ok {
+		e = e.Translate(Language(c.Context))
 		c.R.Code = e.Code
+		c.R.Msg = e.Msg
 		c.R.DebugMsg = e.DebugMsg
 	}
-	c.R.Msg = err.Error()
-	c.JSON(http.StatusOK, c.R)
+	c.render()
 }
 
 // ErrorCodeMsg 直接指定code和msg
 func (c *ServiceContext) ErrorCodeMsg(code int64, msg string) {
 	c.R.Code = code
 	c.R.Msg = msg
-	c.JSON(http.StatusOK, c.R)
+	c.render()
 }
 
 // Response 直接指定code和msg和data
@@ -64,16 +83,17 @@ func (c *ServiceContext) Response(code int64, msg string, data interface{}) {
 	c.R.Code = code
 	c.R.Msg = msg
 	c.R.Data = data
-	c.JSON(http.StatusOK, c.R)
+	c.render()
 }
 
 func (c *ServiceContext) Return(err *code_err.CodeErr) {
 	if err != nil {
+		err = err.Translate(Language(c.Context))
 		c.R.Code = err.Code
 		c.R.Msg = err.Msg
 		c.R.DebugMsg = err.DebugMsg
 	}
-	c.JSON(http.StatusOK, c.R)
+	c.render()
 }
 
 // Success 返回正常数据
@@ -82,6 +102,28 @@ func (c *ServiceContext) SetData(data interface{}) *code_err.CodeErr {
 	return nil
 }
 
+// render 返回 c.R, 请求开启响应加密时返回加密后的 json
+func (c *ServiceContext) render() {
+	key, ok := c.Get(EncryptKey)
+	encryptKey, _ := key.([]byte)
+	if !ok || len(encryptKey) == 0 {
+		c.JSON(http.StatusOK, c.R)
+		return
+	}
+	data, err := json.Marshal(c.R)
+	if err == nil {
+		var body string
+		if body, err = aes.NewAes(encryptKey).Encrypt(string(data)); err == nil {
+			c.Header(EncryptHeader, "1")
+			c.Data(http.StatusOK, gin.MIMEPlain+"; charset=utf-8", []byte(body))
+			return
+		}
+	}
+	// 加密失败时不返回明文数据
+	elog.ErrorCtx(c.Ctx, "encrypt response", elog.FieldError(err))
+	c.JSON(http.StatusOK, Response{Code: code_err.EncryptErr.Code, Msg: code_err.EncryptErr.Msg})
+}
+
 // BindValidator 参数绑定结构体，并且按照tag进行校验返回校验结果
 func (c *ServiceContext) BindValidator(obj interface{}) error {
 	err := c.ShouldBind(obj)
@@ -85,6 +85,8 @@ func (c *Config) NewBackOff() backoff.BackOff {
 		eb.Multiplier = float64(c.Multiplier)
 		eb.MaxInterval = c.MaxInterval
 		eb.MaxElapsedTime = c.MaxElapsedTime
+		// NewExponentialBackOff 按默认的 500ms 初始化了当前间隔, 修改配置后需要 Reset
+		eb.Reset()
 		b = eb
 	}
 
@@ -2,6 +2,29 @@ package retry
 
 import (
 	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
 )
 
 var errRetry = errors.New("Testing")
+
+func TestConfig_NewBackOff(t *testing.T) {
+	c := Config{
+		Policy:              PolicyExponential,
+		InitialInterval:     50 * time.Millisecond,
+		RandomizationFactor: 0.2,
+		Multiplier:          2,
+		MaxInterval:         time.Second,
+		MaxRetries:          2,
+	}
+	b := c.NewBackOff()
+	next := b.NextBackOff()
+	assert.GreaterOrEqual(t, next, 40*time.Millisecond)
+	assert.LessOrEqual(t, next, 60*time.Millisecond)
+	next = b.NextBackOff()
+	assert.GreaterOrEqual(t, next, 80*time.Millisecond)
+	assert.LessOrEqual(t, next, 120*time.Millisecond)
+	assert.Equal(t, time.Duration(-1), b.NextBackOff())
+}
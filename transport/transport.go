@@ -86,6 +86,14 @@ func GetMapFromContext(ctx context.Context) map[string]string {
 	return mp
 }
 
+// WithValue 设置透传参数, 返回新的 ctx, 不影响原 ctx
+// key 需要以 x-pass- 开头或者是已注册的 custom key, 否则下游无法提取
+func WithValue(ctx context.Context, key, value string) context.Context {
+	mp := GetMapFromContext(ctx)
+	mp[strings.ToLower(key)] = value
+	return setMap(ctx, mp)
+}
+
 func GetMapFromPropagator(carrier propagation.TextMapCarrier) map[string]string {
 	mp := make(map[string]string)
 	for _, k := range carrier.Keys() {
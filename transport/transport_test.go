@@ -350,3 +350,15 @@ func BenchmarkCustomKeysMix_10(b *testing.B) {
 func BenchmarkCustomKeysMix_50(b *testing.B) {
 	benchmarkMixLen(b, 50)
 }
+
+func TestWithValue(t *testing.T) {
+	reset()
+	ctx := WithValue(context.Background(), PrefixPass+"uid", "1")
+	ctx2 := WithValue(ctx, PrefixPass+"Lang", "en")
+	assert.Equal(t, map[string]string{PrefixPass + "uid": "1"}, GetMapFromContext(ctx))
+	assert.Equal(t, map[string]string{PrefixPass + "uid": "1", PrefixPass + "lang": "en"}, GetMapFromContext(ctx2))
+
+	md := metadata.MD{}
+	CustomKeysMapPropagator.Inject(ctx2, GrpcHeaderCarrier(md))
+	assert.Equal(t, []string{"en"}, md.Get(PrefixPass+"lang"))
+}